@@ -0,0 +1,124 @@
+package ntree
+
+// FoldUp computes a value for every node of the tree rooted at root in post-order: leaf gives the value of a
+// leaf and combine the value of any other node from the values of its children in order.  combine must not keep
+// the children slice, which is reused.
+func FoldUp(root *Node, leaf func(n *Node) interface{}, combine func(n *Node, children []interface{}) interface{}) map[*Node]interface{} {
+	if root == nil || leaf == nil || combine == nil {
+		return nil
+	}
+
+	results := make(map[*Node]interface{})
+	nodes, _ := postOrder(root)
+	var children []interface{}
+	for _, n := range nodes[1:] {
+		if n.Children == nil {
+			results[n] = leaf(n)
+			continue
+		}
+
+		children = children[:0]
+		for child := n.Children; child != nil; child = child.Next {
+			children = append(children, results[child])
+		}
+		results[n] = combine(n, children)
+	}
+	return results
+}
+
+// FoldUpInto is like FoldUp but passes each value to set as soon as it is computed.
+func FoldUpInto(root *Node, leaf func(n *Node) interface{}, combine func(n *Node, children []interface{}) interface{}, set func(n *Node, v interface{})) {
+	if leaf == nil || combine == nil || set == nil {
+		return
+	}
+
+	FoldUp(root, func(n *Node) interface{} {
+		v := leaf(n)
+		set(n, v)
+		return v
+	}, func(n *Node, children []interface{}) interface{} {
+		v := combine(n, children)
+		set(n, v)
+		return v
+	})
+}
+
+// PropagateDown computes a value for every node of the tree rooted at root in pre-order: root gets init and every
+// other node the result of derive on the node and its parent's value.
+func PropagateDown(root *Node, init interface{}, derive func(n *Node, parent interface{}) interface{}) map[*Node]interface{} {
+	if root == nil || derive == nil {
+		return nil
+	}
+
+	results := map[*Node]interface{}{root: init}
+	for n := nextPreOrder(root, root); n != nil; n = nextPreOrder(root, n) {
+		results[n] = derive(n, results[n.Parent])
+	}
+	return results
+}
+
+// PropagateDownInto is like PropagateDown but passes each value to set as soon as it is computed.
+func PropagateDownInto(root *Node, init interface{}, derive func(n *Node, parent interface{}) interface{}, set func(n *Node, v interface{})) {
+	if root == nil || derive == nil || set == nil {
+		return
+	}
+
+	set(root, init)
+	PropagateDown(root, init, func(n *Node, parent interface{}) interface{} {
+		v := derive(n, parent)
+		set(n, v)
+		return v
+	})
+}
+
+// SubtreeSizes returns the number of nodes in the subtree of every node, counting the node itself.
+func SubtreeSizes(root *Node) map[*Node]int {
+	sizes := make(map[*Node]int)
+	FoldUpInto(root, func(n *Node) interface{} {
+		return 1
+	}, func(n *Node, children []interface{}) interface{} {
+		size := 1
+		for _, c := range children {
+			size += c.(int)
+		}
+		return size
+	}, func(n *Node, v interface{}) {
+		sizes[n] = v.(int)
+	})
+	return sizes
+}
+
+// Heights returns the height of every node, where leaves have height 1 in the same way as the root has Depth 1.
+func Heights(root *Node) map[*Node]int {
+	heights := make(map[*Node]int)
+	FoldUpInto(root, func(n *Node) interface{} {
+		return 1
+	}, func(n *Node, children []interface{}) interface{} {
+		height := 0
+		for _, c := range children {
+			if c.(int) > height {
+				height = c.(int)
+			}
+		}
+		return height + 1
+	}, func(n *Node, v interface{}) {
+		heights[n] = v.(int)
+	})
+	return heights
+}
+
+// WeightedDepths returns the sum of weight over the path from root down to every node, excluding root itself, so
+// that root has weighted depth 0.
+func WeightedDepths(root *Node, weight func(n *Node) float64) map[*Node]float64 {
+	if weight == nil {
+		return nil
+	}
+
+	depths := make(map[*Node]float64)
+	PropagateDownInto(root, 0.0, func(n *Node, parent interface{}) interface{} {
+		return parent.(float64) + weight(n)
+	}, func(n *Node, v interface{}) {
+		depths[n] = v.(float64)
+	})
+	return depths
+}
@@ -0,0 +1,88 @@
+package ntree_test
+
+import (
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func TestFoldUp(t *testing.T) {
+	root := parseLabels("a(b(d e) c(f))")
+	results := ntree.FoldUp(root, func(n *ntree.Node) interface{} {
+		return n.Value.(string)
+	}, func(n *ntree.Node, children []interface{}) interface{} {
+		s := n.Value.(string) + "["
+		for _, c := range children {
+			s += c.(string)
+		}
+		return s + "]"
+	})
+	if results[root] != "a[b[de]c[f]]" || len(results) != 6 {
+		t.Error("Unexpected fold", results[root])
+	}
+
+	var order []string
+	ntree.FoldUpInto(root, func(n *ntree.Node) interface{} {
+		return nil
+	}, func(n *ntree.Node, children []interface{}) interface{} {
+		return nil
+	}, func(n *ntree.Node, v interface{}) {
+		order = append(order, n.Value.(string))
+	})
+	if len(order) != 6 || order[0] != "d" || order[5] != "a" {
+		t.Error("FoldUpInto should set values in post-order", order)
+	}
+}
+
+func TestPropagateDown(t *testing.T) {
+	root := parseLabels("a(b(d e) c(f))")
+	results := ntree.PropagateDown(root, "", func(n *ntree.Node, parent interface{}) interface{} {
+		return parent.(string) + "/" + n.Value.(string)
+	})
+	f := root.Children.Next.Children
+	if results[root] != "" || results[f] != "/c/f" || len(results) != 6 {
+		t.Error("Unexpected propagation", results)
+	}
+
+	values := make(map[*ntree.Node]interface{})
+	ntree.PropagateDownInto(root, 0, func(n *ntree.Node, parent interface{}) interface{} {
+		return parent.(int) + 1
+	}, func(n *ntree.Node, v interface{}) {
+		values[n] = v
+	})
+	if values[root] != 0 || values[f] != 2 {
+		t.Error("Unexpected propagation through a setter", values)
+	}
+}
+
+func TestAggregateHelpers(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	nodes := GenerateRandomTree(r, 300)
+	root := nodes[0]
+
+	sizes := ntree.SubtreeSizes(root)
+	heights := ntree.Heights(root)
+	depths := ntree.WeightedDepths(root, func(n *ntree.Node) float64 {
+		return 0.5
+	})
+	for _, n := range nodes {
+		if sizes[n] != ntree.NodeCount(n, ntree.TraverseAll) {
+			t.Fatal("Wrong subtree size for", n.Value)
+		}
+		if depths[n] != float64(ntree.Depth(n)-1)/2 {
+			t.Fatal("Wrong weighted depth for", n.Value)
+		}
+
+		height := 0
+		ntree.Traverse(n, ntree.TraversePreOrder, ntree.TraverseLeaves, -1, func(leaf *ntree.Node, data interface{}) bool {
+			if d := ntree.Depth(leaf) - ntree.Depth(n) + 1; d > height {
+				height = d
+			}
+			return false
+		}, nil)
+		if heights[n] != height {
+			t.Fatal("Wrong height for", n.Value)
+		}
+	}
+}
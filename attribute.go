@@ -0,0 +1,113 @@
+package ntree
+
+// Attribute is a named value computed for a node from the node and the attribute's values for its children.
+// Values are cached on the nodes and dropped for a node and its ancestors when AppendChild, InsertBefore,
+// Unlink, Move, SortChildren or SetValue change its subtree, so that reads only recompute what changed.  Changes
+// made by assigning to the fields of a Node need an explicit Invalidate.  Reads store into the nodes, so they
+// count as changes when the tree is shared between goroutines.
+type Attribute struct {
+	name    string
+	compute func(n *Node, children []interface{}) interface{}
+}
+
+// NewAttribute returns an attribute called name computed by compute.  compute must not change the tree or keep
+// the children slice, which is reused.
+func NewAttribute(name string, compute func(n *Node, children []interface{}) interface{}) *Attribute {
+	return &Attribute{name: name, compute: compute}
+}
+
+// Name returns the name of the attribute.
+func (a *Attribute) Name() string {
+	return a.name
+}
+
+func (a *Attribute) String() string {
+	return a.name
+}
+
+// Get returns the value of the attribute for n, computing it for n and every node below it that has none cached.
+func (a *Attribute) Get(n *Node) interface{} {
+	if n == nil {
+		return nil
+	}
+	if v, ok := a.cached(n); ok {
+		return v
+	}
+
+	type frame struct {
+		n        *Node
+		expanded bool
+	}
+	stack := []frame{{n: n}}
+	var children []interface{}
+	for len(stack) > 0 {
+		top := len(stack) - 1
+		current := stack[top].n
+		if !stack[top].expanded {
+			stack[top].expanded = true
+			for child := current.Children; child != nil; child = child.Next {
+				if _, ok := a.cached(child); !ok {
+					stack = append(stack, frame{n: child})
+				}
+			}
+			continue
+		}
+
+		stack = stack[:top]
+		children = children[:0]
+		for child := current.Children; child != nil; child = child.Next {
+			v, _ := a.cached(child)
+			children = append(children, v)
+		}
+		ext := current.extension()
+		if ext.attributes == nil {
+			ext.attributes = make(map[*Attribute]interface{})
+		}
+		ext.attributes[a] = a.compute(current, children)
+	}
+	return n.ext.attributes[a]
+}
+
+func (a *Attribute) cached(n *Node) (interface{}, bool) {
+	if n.ext == nil {
+		return nil, false
+	}
+	v, ok := n.ext.attributes[a]
+	return v, ok
+}
+
+// Cached reports whether the value of the attribute for n is cached.
+func (a *Attribute) Cached(n *Node) bool {
+	if n == nil {
+		return false
+	}
+	_, ok := a.cached(n)
+	return ok
+}
+
+// Forget drops the cached values of the attribute in the subtree rooted at n and for its ancestors.
+func (a *Attribute) Forget(n *Node) {
+	if n == nil {
+		return
+	}
+	for current := n; current != nil; current = nextPreOrder(n, current) {
+		if current.ext != nil {
+			delete(current.ext.attributes, a)
+		}
+	}
+	for current := n.Parent; current != nil; current = current.Parent {
+		if current.ext != nil {
+			delete(current.ext.attributes, a)
+		}
+	}
+}
+
+// Invalidate drops the cached attributes of n and its ancestors, after n's value or children were changed
+// directly.
+func Invalidate(n *Node) {
+	// A node only has a cached value when every node below it has one, so the first node without any cached
+	// values has no ancestors with any either.
+	for ; n != nil && n.ext != nil && len(n.ext.attributes) > 0; n = n.Parent {
+		n.ext.attributes = nil
+	}
+}
@@ -0,0 +1,134 @@
+package ntree_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func sumAttribute(computed *int) *ntree.Attribute {
+	return ntree.NewAttribute("sum", func(n *ntree.Node, children []interface{}) interface{} {
+		*computed++
+		sum := n.Value.(int)
+		for _, c := range children {
+			sum += c.(int)
+		}
+		return sum
+	})
+}
+
+func freshSum(n *ntree.Node) int {
+	sum := 0
+	ntree.Traverse(n, ntree.TraversePreOrder, ntree.TraverseAll, -1, func(node *ntree.Node, data interface{}) bool {
+		sum += node.Value.(int)
+		return false
+	}, nil)
+	return sum
+}
+
+func freshShape(n *ntree.Node) string {
+	s := fmt.Sprint(n.Value, "(")
+	for child := n.Children; child != nil; child = child.Next {
+		s += freshShape(child)
+	}
+	return s + ")"
+}
+
+func TestAttributeRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	nodes := GenerateRandomTree(r, 100)
+	computed := 0
+	sum := sumAttribute(&computed)
+	shape := ntree.NewAttribute("shape", func(n *ntree.Node, children []interface{}) interface{} {
+		s := fmt.Sprint(n.Value, "(")
+		for _, c := range children {
+			s += c.(string)
+		}
+		return s + ")"
+	})
+
+	for i := 0; i < 2000; i++ {
+		n, other := nodes[r.Intn(len(nodes))], nodes[r.Intn(len(nodes))]
+		switch r.Intn(7) {
+		case 0:
+			nodes = append(nodes, ntree.AppendChild(n, ntree.New(r.Intn(100))))
+		case 1:
+			ntree.Unlink(n)
+		case 2:
+			ntree.Move(n, other, -1)
+		case 3:
+			ntree.SetValue(n, r.Intn(100))
+		case 4:
+			ntree.SortChildren(n, func(a, b interface{}) bool {
+				return a.(int) < b.(int)
+			})
+		case 5:
+			if n.Parent != nil {
+				nodes = append(nodes, ntree.InsertBefore(n.Parent, n, ntree.New(r.Intn(100))))
+			}
+		case 6:
+			if got := sum.Get(n); got != freshSum(n) {
+				t.Fatalf("Cached sum %v differs from fresh sum %v", got, freshSum(n))
+			}
+			if got := shape.Get(other); got != freshShape(other) {
+				t.Fatalf("Cached shape %v differs from fresh shape %v", got, freshShape(other))
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		if sum.Get(n) != freshSum(n) {
+			t.Fatal("Cached sum differs from a fresh computation")
+		}
+	}
+}
+
+func TestAttributeRecomputesStaleNodes(t *testing.T) {
+	root := ntree.New(1)
+	a := ntree.AppendChild(root, ntree.New(2))
+	b := ntree.AppendChild(root, ntree.New(3))
+	a1 := ntree.AppendChild(a, ntree.New(4))
+	computed := 0
+	sum := sumAttribute(&computed)
+
+	if sum.Get(root) != 10 || computed != 4 {
+		t.Fatal("First read should compute every node", computed)
+	}
+	if sum.Get(root) != 10 || sum.Get(a) != 6 || computed != 4 {
+		t.Error("Cached reads should not recompute")
+	}
+
+	ntree.SetValue(a1, 5)
+	if !sum.Cached(b) || sum.Cached(a) || sum.Cached(root) {
+		t.Error("A value change should only drop the cache along the ancestor chain")
+	}
+	computed = 0
+	if sum.Get(root) != 11 || computed != 3 {
+		t.Error("Only stale nodes should be recomputed, got", computed)
+	}
+
+	ntree.Unlink(b)
+	computed = 0
+	if sum.Get(root) != 8 || computed != 1 || sum.Get(b) != 3 || computed != 1 {
+		t.Error("Unlink should keep the cache of the removed subtree")
+	}
+
+	a1.Value = 0
+	ntree.Invalidate(a1)
+	if sum.Get(root) != 3 {
+		t.Error("Invalidate should drop the caches of direct changes")
+	}
+
+	sum.Forget(a)
+	if sum.Cached(a) || sum.Cached(a1) || sum.Cached(root) || sum.Name() != "sum" {
+		t.Error("Forget should drop the subtree and its ancestors")
+	}
+}
+
+func TestAttributeNodesStayComparable(t *testing.T) {
+	if *ntree.New(1) != *ntree.New(1) {
+		t.Error("Plain nodes with equal fields should be equal")
+	}
+}
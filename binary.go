@@ -0,0 +1,314 @@
+package ntree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// BinaryVersion is the format version written into the header by Encode.
+const BinaryVersion = 1
+
+var binaryMagic = [4]byte{'N', 'T', 'R', 'E'}
+
+var (
+	ErrBadMagic           = errors.New("ntree: bad magic")
+	ErrUnsupportedVersion = errors.New("ntree: unsupported version")
+	ErrChecksum           = errors.New("ntree: checksum mismatch")
+	ErrLimitExceeded      = errors.New("ntree: limit exceeded")
+	ErrMalformed          = errors.New("ntree: malformed input")
+)
+
+// DecodeError is returned by Decode for any input that cannot be decoded.  Offset is the
+// number of bytes consumed when the problem was found.
+type DecodeError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("ntree: decode failed at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ValueCodec converts Node values to and from bytes for Encode and Decode.
+type ValueCodec interface {
+	EncodeValue(v interface{}) ([]byte, error)
+	DecodeValue(b []byte) (interface{}, error)
+}
+
+// StringCodec encodes string values.
+type StringCodec struct{}
+
+func (StringCodec) EncodeValue(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("ntree: StringCodec cannot encode %T", v)
+	}
+	return []byte(s), nil
+}
+
+func (StringCodec) DecodeValue(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+// BytesCodec encodes []byte values.
+type BytesCodec struct{}
+
+func (BytesCodec) EncodeValue(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("ntree: BytesCodec cannot encode %T", v)
+	}
+	return b, nil
+}
+
+func (BytesCodec) DecodeValue(b []byte) (interface{}, error) {
+	return b, nil
+}
+
+// BinaryLimits bounds the resources Decode is allowed to use.
+type BinaryLimits struct {
+	MaxNodes     int
+	MaxValueSize int
+}
+
+// DefaultBinaryLimits is used by Decode.
+var DefaultBinaryLimits = BinaryLimits{
+	MaxNodes:     1 << 24,
+	MaxValueSize: 1 << 24,
+}
+
+// Encode writes the tree rooted at root to w.  The format is a header (magic and version), the nodes
+// in pre-order as (value length, value, child count) with varint lengths, and a CRC32 trailer.
+func Encode(w io.Writer, root *Node, codec ValueCodec) error {
+	if root == nil || codec == nil {
+		return errors.New("ntree: nil root or codec")
+	}
+
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	out := io.MultiWriter(bw, crc)
+
+	var scratch [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) error {
+		_, err := out.Write(scratch[:binary.PutUvarint(scratch[:], v)])
+		return err
+	}
+
+	if _, err := out.Write(binaryMagic[:]); err != nil {
+		return err
+	}
+	if err := putUvarint(BinaryVersion); err != nil {
+		return err
+	}
+
+	n := root
+	for n != nil {
+		b, err := codec.EncodeValue(n.Value)
+		if err != nil {
+			return err
+		}
+		if err := putUvarint(uint64(len(b))); err != nil {
+			return err
+		}
+		if _, err := out.Write(b); err != nil {
+			return err
+		}
+
+		children := 0
+		for child := n.Children; child != nil; child = child.Next {
+			children++
+		}
+		if err := putUvarint(uint64(children)); err != nil {
+			return err
+		}
+
+		n = nextPreOrder(root, n)
+	}
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	if _, err := bw.Write(sum[:]); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// nextPreOrder returns the node after n in a pre-order walk of root, or nil when the walk is done.
+func nextPreOrder(root, n *Node) *Node {
+	if n.Children != nil {
+		return n.Children
+	}
+
+	for n != root {
+		if n.Next != nil {
+			return n.Next
+		}
+		n = n.Parent
+	}
+
+	return nil
+}
+
+// Decode reads a tree written by Encode using DefaultBinaryLimits.
+func Decode(r io.Reader, codec ValueCodec) (*Node, error) {
+	return DecodeLimited(r, codec, DefaultBinaryLimits)
+}
+
+// DecodeLimited reads a tree written by Encode.  If r is not an io.ByteReader it is buffered, so it may be read
+// past the end of the encoded tree.  Every failure is reported as a *DecodeError.
+func DecodeLimited(r io.Reader, codec ValueCodec, limits BinaryLimits) (*Node, error) {
+	if codec == nil {
+		return nil, errors.New("ntree: nil codec")
+	}
+
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		b := bufio.NewReader(r)
+		r, br = b, b
+	}
+	d := &binaryReader{r: r, br: br, crc: crc32.NewIEEE()}
+
+	root, err := d.decode(codec, limits)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, &DecodeError{Offset: d.offset, Err: err}
+	}
+	return root, nil
+}
+
+type binaryReader struct {
+	r      io.Reader
+	br     io.ByteReader
+	crc    hash.Hash32
+	offset int64
+}
+
+func (d *binaryReader) ReadByte() (byte, error) {
+	c, err := d.br.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.offset++
+	d.crc.Write([]byte{c})
+	return c, nil
+}
+
+// readN reads n bytes in bounded chunks so that a corrupt length cannot force a large allocation up front.
+func (d *binaryReader) readN(n int) ([]byte, error) {
+	const chunk = 64 << 10
+
+	buf := make([]byte, 0, minInt(n, chunk))
+	for len(buf) < n {
+		start := len(buf)
+		end := start + minInt(n-start, chunk)
+		if end > cap(buf) {
+			grown := make([]byte, start, minInt(n, 2*end))
+			copy(grown, buf)
+			buf = grown
+		}
+		buf = buf[:end]
+		read, err := io.ReadFull(d.r, buf[start:end])
+		d.offset += int64(read)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.crc.Write(buf)
+	return buf, nil
+}
+
+func (d *binaryReader) decode(codec ValueCodec, limits BinaryLimits) (*Node, error) {
+	var magic [4]byte
+	for i := range magic {
+		c, err := d.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		magic[i] = c
+	}
+	if magic != binaryMagic {
+		return nil, ErrBadMagic
+	}
+
+	version, err := binary.ReadUvarint(d)
+	if err != nil {
+		return nil, err
+	}
+	if version != BinaryVersion {
+		return nil, ErrUnsupportedVersion
+	}
+
+	var root *Node
+	b := treeBuilder{}
+	count := 0
+	for root == nil || !b.done() {
+		count++
+		if count > limits.MaxNodes {
+			return nil, ErrLimitExceeded
+		}
+
+		size, err := binary.ReadUvarint(d)
+		if err != nil {
+			return nil, err
+		}
+		if size > uint64(limits.MaxValueSize) {
+			return nil, ErrLimitExceeded
+		}
+		value, err := d.readN(int(size))
+		if err != nil {
+			return nil, err
+		}
+		v, err := codec.DecodeValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		children, err := binary.ReadUvarint(d)
+		if err != nil {
+			return nil, err
+		}
+		if children > uint64(limits.MaxNodes-count) {
+			return nil, ErrLimitExceeded
+		}
+
+		n := New(v)
+		if root == nil {
+			root = n
+		}
+		b.add(n, int(children))
+	}
+
+	want := d.crc.Sum32()
+	var sum [4]byte
+	for i := range sum {
+		c, err := d.br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		d.offset++
+		sum[i] = c
+	}
+	if binary.BigEndian.Uint32(sum[:]) != want {
+		return nil, ErrChecksum
+	}
+
+	return root, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,178 @@
+package ntree_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func GenerateStringTree() *ntree.Node {
+	root := ntree.New("root")
+	a := ntree.AppendChild(root, ntree.New("a"))
+	ntree.AppendChild(a, ntree.New("a1"))
+	ntree.AppendChild(a, ntree.New("a2"))
+	b := ntree.AppendChild(root, ntree.New("b"))
+	b1 := ntree.AppendChild(b, ntree.New("b1"))
+	ntree.AppendChild(b1, ntree.New("b1x"))
+	ntree.AppendChild(root, ntree.New(""))
+	return root
+}
+
+func sameTree(a, b *ntree.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Value != b.Value {
+		return false
+	}
+
+	ca, cb := a.Children, b.Children
+	for ca != nil && cb != nil {
+		if ca.Parent != a || cb.Parent != b || !sameTree(ca, cb) {
+			return false
+		}
+		if ca.Next != nil && ca.Next.Previous != ca || cb.Next != nil && cb.Next.Previous != cb {
+			return false
+		}
+		ca, cb = ca.Next, cb.Next
+	}
+	return ca == nil && cb == nil
+}
+
+func encodeTree(t testing.TB, root *ntree.Node) []byte {
+	var buf bytes.Buffer
+	if err := ntree.Encode(&buf, root, ntree.StringCodec{}); err != nil {
+		t.Fatal("Encode failed:", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeDecode(t *testing.T) {
+	root := GenerateStringTree()
+	data := encodeTree(t, root)
+
+	decoded, err := ntree.Decode(bytes.NewReader(data), ntree.StringCodec{})
+	if err != nil {
+		t.Fatal("Decode failed:", err)
+	}
+	if !sameTree(root, decoded) {
+		t.Error("Decoded tree does not match the encoded one", decoded)
+	}
+
+	single, err := ntree.Decode(bytes.NewReader(encodeTree(t, ntree.New("x"))), ntree.StringCodec{})
+	if err != nil || single.Value != "x" || single.Children != nil {
+		t.Error("Single node round trip failed", err)
+	}
+
+	if ntree.Encode(&bytes.Buffer{}, nil, ntree.StringCodec{}) == nil {
+		t.Error("Encode should fail for a nil root")
+	}
+	if ntree.Encode(&bytes.Buffer{}, ntree.New(1), ntree.StringCodec{}) == nil {
+		t.Error("Encode should fail when the codec rejects a value")
+	}
+}
+
+func TestDecodeWideTree(t *testing.T) {
+	root := ntree.New("root")
+	for i := 0; i < 10000; i++ {
+		ntree.AppendChild(root, ntree.New("leaf"))
+	}
+	chain := root.Children
+	for i := 0; i < 10000; i++ {
+		chain = ntree.AppendChild(chain, ntree.New("deep"))
+	}
+
+	decoded, err := ntree.Decode(bytes.NewReader(encodeTree(t, root)), ntree.StringCodec{})
+	if err != nil {
+		t.Fatal("Decode failed:", err)
+	}
+	if ntree.NodeCount(decoded, ntree.TraverseAll) != ntree.NodeCount(root, ntree.TraverseAll) {
+		t.Error("Mismatched node count after decoding")
+	}
+}
+
+func TestDecodeCorrupted(t *testing.T) {
+	data := encodeTree(t, GenerateStringTree())
+
+	check := func(input []byte, target error) {
+		root, err := ntree.Decode(bytes.NewReader(input), ntree.StringCodec{})
+		if root != nil || err == nil {
+			t.Error("Decode should fail for corrupted input", input)
+			return
+		}
+		var decodeErr *ntree.DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("Expected a *DecodeError but got %T", err)
+		}
+		if target != nil && !errors.Is(err, target) {
+			t.Errorf("Expected %v but got %v", target, err)
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		check(data[:i], nil)
+	}
+	for i := 0; i < len(data); i++ {
+		flipped := append([]byte(nil), data...)
+		flipped[i] ^= 0x40
+		check(flipped, nil)
+	}
+
+	badMagic := append([]byte(nil), data...)
+	badMagic[0] = 'X'
+	check(badMagic, ntree.ErrBadMagic)
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[4] = 2
+	check(badVersion, ntree.ErrUnsupportedVersion)
+
+	badSum := append([]byte(nil), data...)
+	badSum[len(badSum)-1]++
+	check(badSum, ntree.ErrChecksum)
+
+	huge := []byte{'N', 'T', 'R', 'E', 1, 0xff, 0xff, 0xff, 0xff, 0x0f}
+	check(huge, ntree.ErrLimitExceeded)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	data := encodeTree(t, GenerateStringTree())
+
+	_, err := ntree.DecodeLimited(bytes.NewReader(data), ntree.StringCodec{}, ntree.BinaryLimits{MaxNodes: 3, MaxValueSize: 16})
+	if !errors.Is(err, ntree.ErrLimitExceeded) {
+		t.Error("MaxNodes should be enforced", err)
+	}
+
+	_, err = ntree.DecodeLimited(bytes.NewReader(data), ntree.StringCodec{}, ntree.BinaryLimits{MaxNodes: 100, MaxValueSize: 2})
+	if !errors.Is(err, ntree.ErrLimitExceeded) {
+		t.Error("MaxValueSize should be enforced", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(encodeTree(f, GenerateStringTree()))
+	f.Add(encodeTree(f, ntree.New("x")))
+	f.Add([]byte{'N', 'T', 'R', 'E', 1})
+
+	limits := ntree.BinaryLimits{MaxNodes: 1 << 12, MaxValueSize: 1 << 12}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		root, err := ntree.DecodeLimited(bytes.NewReader(data), ntree.BytesCodec{}, limits)
+		if err != nil {
+			var decodeErr *ntree.DecodeError
+			if root != nil || !errors.As(err, &decodeErr) {
+				t.Fatalf("Unexpected result %v, %v", root, err)
+			}
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := ntree.Encode(&buf, root, ntree.BytesCodec{}); err != nil {
+			t.Fatal("Re-encoding a decoded tree failed:", err)
+		}
+		again, err := ntree.Decode(&buf, ntree.BytesCodec{})
+		if err != nil || ntree.NodeCount(again, ntree.TraverseAll) != ntree.NodeCount(root, ntree.TraverseAll) {
+			t.Error("Re-encoded tree does not decode to the same shape", err)
+		}
+	})
+}
@@ -0,0 +1,386 @@
+package ntree
+
+import (
+	"errors"
+	"fmt"
+)
+
+type EditKind int
+
+const (
+	EditUpdate EditKind = iota
+	EditDelete
+	EditInsert
+	EditMove
+)
+
+func (k EditKind) String() string {
+	switch k {
+	case EditUpdate:
+		return "update"
+	case EditDelete:
+		return "delete"
+	case EditInsert:
+		return "insert"
+	case EditMove:
+		return "move"
+	}
+	return fmt.Sprintf("EditKind(%d)", int(k))
+}
+
+// EditOp is one step of an edit script.  Paths are child indices from the root, and are resolved against the tree
+// as it is when the op is applied, after every earlier op:
+//   - EditUpdate sets the Value of the node at Path.
+//   - EditDelete unlinks the node at Path.
+//   - EditInsert inserts a copy of Node so that it ends up at Path.
+//   - EditMove moves the node at Path to index To among its siblings, counted once it has been taken out.
+type EditOp struct {
+	Kind  EditKind
+	Path  []int
+	To    int
+	Value interface{}
+	Node  *Node
+}
+
+func (op EditOp) String() string {
+	switch op.Kind {
+	case EditUpdate:
+		return fmt.Sprintf("update %v = %v", op.Path, op.Value)
+	case EditInsert:
+		return fmt.Sprintf("insert %v (%v)", op.Path, op.Node.Value)
+	case EditMove:
+		return fmt.Sprintf("move %v to %d", op.Path, op.To)
+	}
+	return fmt.Sprintf("%v %v", op.Kind, op.Path)
+}
+
+// DiffOptions configures Diff.  Equal compares values and defaults to ==.  When Key is set, children are matched
+// by key (the first child with each key wins); otherwise they are matched by the longest common subsequence of
+// equal values, and the children left between two matches are paired in order.  IgnoreOrder drops the moves
+// that only reorder siblings.
+type DiffOptions struct {
+	Equal       func(a, b interface{}) bool
+	Key         func(n *Node) interface{}
+	IgnoreOrder bool
+}
+
+// Diff returns an edit script that turns the tree rooted at a into the one rooted at b.  Matched nodes only move
+// among their siblings; a node that changes parent is deleted and inserted.
+func Diff(a, b *Node, opts DiffOptions) []EditOp {
+	if a == nil || b == nil {
+		return nil
+	}
+	if opts.Equal == nil {
+		opts.Equal = func(x, y interface{}) bool {
+			return x == y
+		}
+	}
+
+	var ops []EditOp
+	diffNode(a, b, nil, opts, &ops)
+	return ops
+}
+
+func diffNode(x, y *Node, path []int, opts DiffOptions, ops *[]EditOp) {
+	if !opts.Equal(x.Value, y.Value) {
+		*ops = append(*ops, EditOp{Kind: EditUpdate, Path: clonePath(path), Value: y.Value})
+	}
+
+	from, to := childList(x), childList(y)
+	match := matchChildren(from, to, opts)
+
+	// Delete unmatched children from the back so that earlier indices stay valid.
+	var current []*Node
+	for i := len(from) - 1; i >= 0; i-- {
+		if match[i] < 0 {
+			*ops = append(*ops, EditOp{Kind: EditDelete, Path: childPath(path, i)})
+		}
+	}
+	matchedTo := make([]*Node, len(to))
+	for i, child := range from {
+		if match[i] >= 0 {
+			current = append(current, child)
+			matchedTo[match[i]] = child
+		}
+	}
+
+	// Children on the longest increasing run of target positions stay where they are; every other matched child
+	// is moved after its predecessor in the target order, and unmatched targets are inserted there.
+	stable := make(map[*Node]bool)
+	var targets []int
+	for i := range from {
+		if match[i] >= 0 {
+			targets = append(targets, match[i])
+		}
+	}
+	for _, k := range longestIncreasing(targets) {
+		stable[current[k]] = true
+	}
+
+	final := make([]*Node, len(to))
+	for j, target := range to {
+		source := matchedTo[j]
+		if source != nil && (stable[source] || opts.IgnoreOrder) {
+			final[j] = source
+			continue
+		}
+
+		index := len(current)
+		if !opts.IgnoreOrder {
+			index = 0
+			if j > 0 {
+				index = indexOf(current, final[j-1]) + 1
+			}
+		}
+
+		if source == nil {
+			*ops = append(*ops, EditOp{Kind: EditInsert, Path: childPath(path, index), Node: Copy(target)})
+			source = target
+		} else {
+			at := indexOf(current, source)
+			current = append(current[:at], current[at+1:]...)
+			if at < index {
+				index--
+			}
+			*ops = append(*ops, EditOp{Kind: EditMove, Path: childPath(path, at), To: index})
+		}
+		current = append(current[:index], append([]*Node{source}, current[index:]...)...)
+		final[j] = source
+	}
+
+	for j, source := range matchedTo {
+		if source != nil {
+			diffNode(source, to[j], childPath(path, indexOf(current, source)), opts, ops)
+		}
+	}
+}
+
+func childList(n *Node) []*Node {
+	var children []*Node
+	for child := n.Children; child != nil; child = child.Next {
+		children = append(children, child)
+	}
+	return children
+}
+
+func clonePath(path []int) []int {
+	return append([]int{}, path...)
+}
+
+func childPath(path []int, i int) []int {
+	return append(clonePath(path), i)
+}
+
+func indexOf(nodes []*Node, n *Node) int {
+	for i, node := range nodes {
+		if node == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchChildren returns, for each child in from, the index of its match in to or -1.
+func matchChildren(from, to []*Node, opts DiffOptions) []int {
+	match := make([]int, len(from))
+	for i := range match {
+		match[i] = -1
+	}
+
+	switch {
+	case opts.Key != nil:
+		keys := make(map[interface{}]int)
+		for j := len(to) - 1; j >= 0; j-- {
+			keys[opts.Key(to[j])] = j
+		}
+		for i, child := range from {
+			if j, ok := keys[opts.Key(child)]; ok {
+				match[i] = j
+				delete(keys, opts.Key(child))
+			}
+		}
+	case opts.IgnoreOrder:
+		used := make([]bool, len(to))
+		for i, child := range from {
+			for j, target := range to {
+				if !used[j] && opts.Equal(child.Value, target.Value) {
+					match[i], used[j] = j, true
+					break
+				}
+			}
+		}
+	default:
+		lcs := make([][]int, len(from)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(to)+1)
+		}
+		for i := len(from) - 1; i >= 0; i-- {
+			for j := len(to) - 1; j >= 0; j-- {
+				if opts.Equal(from[i].Value, to[j].Value) {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(from) && j < len(to); {
+			switch {
+			case opts.Equal(from[i].Value, to[j].Value):
+				match[i] = j
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+
+		// Pair the children left between two matches in order, so that they are updated rather than deleted
+		// and inserted.
+		i0, j0 := -1, -1
+		for i := 0; i <= len(from); i++ {
+			if i < len(from) && match[i] < 0 {
+				continue
+			}
+			j1 := len(to)
+			if i < len(from) {
+				j1 = match[i]
+			}
+			for k, j := i0+1, j0+1; k < i && j < j1; k, j = k+1, j+1 {
+				match[k] = j
+			}
+			i0, j0 = i, j1
+		}
+	}
+
+	return match
+}
+
+// longestIncreasing returns the indices of a longest strictly increasing subsequence of values.
+func longestIncreasing(values []int) []int {
+	var tails []int
+	previous := make([]int, len(values))
+	for i, v := range values {
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if values[tails[mid]] < v {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		previous[i] = -1
+		if lo > 0 {
+			previous[i] = tails[lo-1]
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+
+	result := make([]int, len(tails))
+	for i, k := len(tails)-1, -1; i >= 0; i-- {
+		if k < 0 {
+			k = tails[len(tails)-1]
+		} else {
+			k = previous[k]
+		}
+		result[i] = k
+	}
+	return result
+}
+
+// Patch applies an edit script produced by Diff to the tree rooted at root.  It stops at the first op whose path
+// does not resolve, leaving the earlier ops applied.
+func Patch(root *Node, ops []EditOp) error {
+	if root == nil {
+		return errors.New("ntree: nil root")
+	}
+
+	for _, op := range ops {
+		if op.Kind != EditUpdate && len(op.Path) == 0 {
+			return fmt.Errorf("ntree: cannot %v the root", op.Kind)
+		}
+
+		switch op.Kind {
+		case EditUpdate:
+			n := childAtPath(root, op.Path)
+			if n == nil {
+				return fmt.Errorf("ntree: no node at %v", op.Path)
+			}
+			SetValue(n, op.Value)
+		case EditDelete:
+			n := childAtPath(root, op.Path)
+			if n == nil {
+				return fmt.Errorf("ntree: no node at %v", op.Path)
+			}
+			Unlink(n)
+		case EditInsert:
+			parent := childAtPath(root, op.Path[:len(op.Path)-1])
+			if parent == nil || op.Node == nil || !insertAt(parent, op.Path[len(op.Path)-1], Copy(op.Node)) {
+				return fmt.Errorf("ntree: cannot insert at %v", op.Path)
+			}
+		case EditMove:
+			n := childAtPath(root, op.Path)
+			if n == nil {
+				return fmt.Errorf("ntree: no node at %v", op.Path)
+			}
+			// To counts the siblings without n, so it must name one of them; check before unlinking so that a bad
+			// move leaves n in place.
+			siblings := -1
+			for child := n.Parent.Children; child != nil; child = child.Next {
+				siblings++
+			}
+			if op.To < 0 || op.To > siblings {
+				return fmt.Errorf("ntree: cannot move %v to %d", op.Path, op.To)
+			}
+			parent := n.Parent
+			Unlink(n)
+			insertAt(parent, op.To, n)
+		default:
+			return fmt.Errorf("ntree: unknown edit %v", op.Kind)
+		}
+	}
+
+	return nil
+}
+
+// childAtPath resolves a path of child indices below root.
+func childAtPath(root *Node, path []int) *Node {
+	n := root
+	for _, i := range path {
+		if i < 0 {
+			return nil
+		}
+		n = n.Children
+		for ; n != nil && i > 0; i-- {
+			n = n.Next
+		}
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// insertAt inserts n so that it becomes child number i of parent.
+func insertAt(parent *Node, i int, n *Node) bool {
+	if i < 0 {
+		return false
+	}
+
+	sibling := parent.Children
+	for ; sibling != nil && i > 0; i-- {
+		sibling = sibling.Next
+	}
+	if i > 0 {
+		return false
+	}
+	return InsertBefore(parent, sibling, n) != nil
+}
@@ -0,0 +1,174 @@
+package ntree_test
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+// mutateTree applies count random inserts, deletes, moves and value changes to the tree rooted at nodes[0].
+func mutateTree(r *rand.Rand, nodes []*ntree.Node, count int, next *int) []*ntree.Node {
+	for i := 0; i < count; i++ {
+		n := nodes[r.Intn(len(nodes))]
+		switch r.Intn(4) {
+		case 0:
+			*next++
+			nodes = append(nodes, ntree.AppendChild(n, ntree.New(*next)))
+		case 1:
+			if n != nodes[0] {
+				ntree.Unlink(n)
+				var kept []*ntree.Node
+				for _, node := range nodes {
+					if root, _ := ntree.GetRoot(node); root == nodes[0] {
+						kept = append(kept, node)
+					}
+				}
+				nodes = kept
+			}
+		case 2:
+			if n != nodes[0] && n.Parent.Children != n {
+				parent := n.Parent
+				ntree.Unlink(n)
+				ntree.InsertBefore(parent, parent.Children, n)
+			}
+		case 3:
+			*next++
+			n.Value = *next
+		}
+	}
+	return nodes
+}
+
+func unorderedString(n *ntree.Node) string {
+	var children []string
+	for child := n.Children; child != nil; child = child.Next {
+		children = append(children, unorderedString(child))
+	}
+	sort.Strings(children)
+	return fmt.Sprintf("%v(%s)", n.Value, strings.Join(children, " "))
+}
+
+func checkEditScript(t *testing.T, a, b *ntree.Node, opts ntree.DiffOptions, expected []string) {
+	ops := ntree.Diff(a, b, opts)
+	if len(ops) != len(expected) {
+		t.Fatal("Unexpected edit script", ops)
+	}
+	for i, op := range ops {
+		if op.String() != expected[i] {
+			t.Errorf("Expected %s but got %s", expected[i], op.String())
+		}
+	}
+
+	if err := ntree.Patch(a, ops); err != nil || !sameTree(a, b) {
+		t.Error("Patch(a, Diff(a, b)) should equal b", err)
+	}
+	if len(ntree.Diff(a, b, opts)) != 0 {
+		t.Error("Diff of equal trees should be empty")
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	a := GenerateStringTree()
+	b := ntree.Copy(a)
+	ntree.AppendChild(b.Children, ntree.New("a3"))
+	ntree.Unlink(b.Children.Next.Children)
+	b.Children.Next.Value = "B"
+	checkEditScript(t, a, b, ntree.DiffOptions{}, []string{"insert [0 2] (a3)", "update [1] = B", "delete [1 0]"})
+
+	a = GenerateStringTree()
+	b = ntree.Copy(a)
+	last := b.Children.Next.Next
+	ntree.Unlink(last)
+	ntree.InsertBefore(b, b.Children, last)
+	ntree.Unlink(b.Children.Next.Children.Next)
+	ntree.AppendChild(b.Children.Next, ntree.New("a2"))
+	key := func(n *ntree.Node) interface{} {
+		return n.Value
+	}
+	checkEditScript(t, a, b, ntree.DiffOptions{Key: key}, []string{"move [2] to 0"})
+	if ops := ntree.Diff(a, ntree.New("x"), ntree.DiffOptions{Key: key, IgnoreOrder: true}); len(ops) != 4 || ops[0].Kind != ntree.EditUpdate {
+		t.Error("Unexpected edit script", ops)
+	}
+}
+
+func TestDiffPatchRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	key := func(n *ntree.Node) interface{} {
+		return n.Value
+	}
+
+	for i := 0; i < 200; i++ {
+		next := 1000
+		nodes := GenerateRandomTree(r, 1+r.Intn(30))
+		a := nodes[0]
+		b := ntree.Copy(a)
+		bNodes := []*ntree.Node{b}
+		for n := b.Children; n != nil; {
+			bNodes = append(bNodes, n)
+			if n.Children != nil {
+				n = n.Children
+				continue
+			}
+			for n != b && n.Next == nil {
+				n = n.Parent
+			}
+			if n == b {
+				break
+			}
+			n = n.Next
+		}
+		mutateTree(r, bNodes, 1+r.Intn(10), &next)
+
+		for _, opts := range []ntree.DiffOptions{{}, {Key: key}, {IgnoreOrder: true}, {Key: key, IgnoreOrder: true}} {
+			patched := ntree.Copy(a)
+			ops := ntree.Diff(patched, b, opts)
+			if err := ntree.Patch(patched, ops); err != nil {
+				t.Fatal("Patch failed:", err, ops)
+			}
+			if opts.IgnoreOrder {
+				if unorderedString(patched) != unorderedString(b) {
+					t.Fatal("Patch(a, Diff(a, b)) should equal b ignoring order", ops)
+				}
+			} else if !sameTree(patched, b) {
+				t.Fatal("Patch(a, Diff(a, b)) should equal b", ops)
+			}
+		}
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	root := GenerateStringTree()
+	bad := [][]ntree.EditOp{
+		{{Kind: ntree.EditDelete}},
+		{{Kind: ntree.EditDelete, Path: []int{9}}},
+		{{Kind: ntree.EditUpdate, Path: []int{0, 9}}},
+		{{Kind: ntree.EditInsert, Path: []int{0, 5}, Node: ntree.New("x")}},
+		{{Kind: ntree.EditMove, Path: []int{0}, To: 7}},
+		{{Kind: ntree.EditKind(9), Path: []int{0}}},
+	}
+	for _, ops := range bad {
+		if ntree.Patch(root, ops) == nil {
+			t.Error("Patch should fail for", ops)
+		}
+	}
+	if !ntree.Equal(root, GenerateStringTree(), nil) {
+		t.Error("Failed ops should leave the tree unchanged")
+	}
+
+	moved := GenerateStringTree()
+	ops := []ntree.EditOp{{Kind: ntree.EditMove, Path: []int{0}, To: 1}, {Kind: ntree.EditMove, Path: []int{1}, To: 3}}
+	if ntree.Patch(moved, ops) == nil || ntree.NodeCount(moved, ntree.TraverseAll) != ntree.NodeCount(root, ntree.TraverseAll) {
+		t.Error("A bad move should fail without losing the node")
+	}
+	if first := moved.Children; first.Value != root.Children.Next.Value || first.Next.Value != root.Children.Value {
+		t.Error("Ops before a failed one should stay applied")
+	}
+
+	if ntree.Patch(nil, nil) == nil || ntree.Diff(nil, root, ntree.DiffOptions{}) != nil {
+		t.Error("Diff and Patch should reject a nil root")
+	}
+}
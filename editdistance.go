@@ -0,0 +1,243 @@
+package ntree
+
+import (
+	"sort"
+)
+
+// EditCosts prices the operations of a tree edit.  Each function receives Node values; nil functions default to
+// unit costs, with Relabel free for values that are ==.
+type EditCosts struct {
+	Insert  func(v interface{}) float64
+	Delete  func(v interface{}) float64
+	Relabel func(a, b interface{}) float64
+}
+
+// NodePair is one node of each tree matched by EditDistanceMapping.
+type NodePair struct {
+	A, B *Node
+}
+
+// EditDistance returns the Zhang-Shasha edit distance between the ordered trees rooted at a and b.  It takes
+// O(|a|*|b|) time per keyroot pair and keeps two |a|*|b| float64 tables.
+func EditDistance(a, b *Node, costs EditCosts) float64 {
+	z := newZhangShasha(a, b, costs)
+	z.run()
+	return z.distance()
+}
+
+// EditDistanceMapping returns the edit distance as EditDistance does, together with the pairs of nodes that are
+// kept (possibly relabelled) by a cheapest edit, ordered by the post-order of a.
+func EditDistanceMapping(a, b *Node, costs EditCosts) (float64, []NodePair) {
+	z := newZhangShasha(a, b, costs)
+	z.run()
+	return z.distance(), z.mapping()
+}
+
+type zhangShasha struct {
+	costs          EditCosts
+	nodesA, nodesB []*Node
+	leftA, leftB   []int
+	deleteCost     []float64
+	insertCost     []float64
+	td             []float64
+	fd             []float64
+	stride         int
+}
+
+// postOrder returns the nodes below root in post-order and the post-order index of each one's leftmost leaf, both
+// indexed from 1.
+func postOrder(root *Node) ([]*Node, []int) {
+	nodes := []*Node{nil}
+	left := []int{0}
+	if root == nil {
+		return nodes, left
+	}
+
+	var firsts []int
+	n := root
+	for {
+		for n.Children != nil {
+			firsts = append(firsts, len(nodes))
+			n = n.Children
+		}
+		nodes = append(nodes, n)
+		left = append(left, len(nodes)-1)
+
+		for n != root && n.Next == nil {
+			n = n.Parent
+			nodes = append(nodes, n)
+			left = append(left, firsts[len(firsts)-1])
+			firsts = firsts[:len(firsts)-1]
+		}
+		if n == root {
+			return nodes, left
+		}
+		n = n.Next
+	}
+}
+
+func newZhangShasha(a, b *Node, costs EditCosts) *zhangShasha {
+	if costs.Insert == nil {
+		costs.Insert = func(interface{}) float64 { return 1 }
+	}
+	if costs.Delete == nil {
+		costs.Delete = func(interface{}) float64 { return 1 }
+	}
+	if costs.Relabel == nil {
+		costs.Relabel = func(x, y interface{}) float64 {
+			if x == y {
+				return 0
+			}
+			return 1
+		}
+	}
+
+	z := &zhangShasha{costs: costs}
+	z.nodesA, z.leftA = postOrder(a)
+	z.nodesB, z.leftB = postOrder(b)
+	z.deleteCost = make([]float64, len(z.nodesA))
+	for i := 1; i < len(z.nodesA); i++ {
+		z.deleteCost[i] = costs.Delete(z.nodesA[i].Value)
+	}
+	z.insertCost = make([]float64, len(z.nodesB))
+	for j := 1; j < len(z.nodesB); j++ {
+		z.insertCost[j] = costs.Insert(z.nodesB[j].Value)
+	}
+
+	z.stride = len(z.nodesB)
+	z.td = make([]float64, len(z.nodesA)*len(z.nodesB))
+	z.fd = make([]float64, len(z.nodesA)*len(z.nodesB))
+	return z
+}
+
+func keyroots(left []int) []int {
+	seen := make(map[int]bool)
+	var roots []int
+	for i := len(left) - 1; i > 0; i-- {
+		if !seen[left[i]] {
+			seen[left[i]] = true
+			roots = append(roots, i)
+		}
+	}
+	for i, j := 0, len(roots)-1; i < j; i, j = i+1, j-1 {
+		roots[i], roots[j] = roots[j], roots[i]
+	}
+	return roots
+}
+
+func (z *zhangShasha) run() {
+	rootsB := keyroots(z.leftB)
+	for _, i := range keyroots(z.leftA) {
+		for _, j := range rootsB {
+			z.forestDistance(i, j)
+		}
+	}
+}
+
+func (z *zhangShasha) distance() float64 {
+	n, m := len(z.nodesA)-1, len(z.nodesB)-1
+	switch {
+	case n == 0 && m == 0:
+		return 0
+	case n == 0:
+		return sumFloats(z.insertCost)
+	case m == 0:
+		return sumFloats(z.deleteCost)
+	}
+	return z.td[n*z.stride+m]
+}
+
+func sumFloats(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// forestDistance fills fd for the forests ending at i and j, recording the distance of every pair of subtrees
+// that share their leftmost leaves in td.  Row li-1 and column lj-1 of fd hold the empty forests.
+func (z *zhangShasha) forestDistance(i, j int) {
+	li, lj := z.leftA[i], z.leftB[j]
+	w := z.stride
+	fd := func(r, c int) *float64 {
+		return &z.fd[(r-li+1)*w+(c-lj+1)]
+	}
+
+	*fd(li-1, lj-1) = 0
+	for r := li; r <= i; r++ {
+		*fd(r, lj-1) = *fd(r-1, lj-1) + z.deleteCost[r]
+	}
+	for c := lj; c <= j; c++ {
+		*fd(li-1, c) = *fd(li-1, c-1) + z.insertCost[c]
+	}
+
+	for r := li; r <= i; r++ {
+		for c := lj; c <= j; c++ {
+			best := *fd(r-1, c) + z.deleteCost[r]
+			if insert := *fd(r, c-1) + z.insertCost[c]; insert < best {
+				best = insert
+			}
+			if z.leftA[r] == li && z.leftB[c] == lj {
+				if relabel := *fd(r-1, c-1) + z.costs.Relabel(z.nodesA[r].Value, z.nodesB[c].Value); relabel < best {
+					best = relabel
+				}
+				z.td[r*w+c] = best
+			} else if subtree := *fd(z.leftA[r]-1, z.leftB[c]-1) + z.td[r*w+c]; subtree < best {
+				best = subtree
+			}
+			*fd(r, c) = best
+		}
+	}
+}
+
+// mapping backtracks through the forest distances, recomputing them for each pair of subtrees it descends into.
+func (z *zhangShasha) mapping() []NodePair {
+	var pairs []NodePair
+	if len(z.nodesA) == 1 || len(z.nodesB) == 1 {
+		return pairs
+	}
+
+	w := z.stride
+	stack := [][2]int{{len(z.nodesA) - 1, len(z.nodesB) - 1}}
+	for len(stack) > 0 {
+		i, j := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+		z.forestDistance(i, j)
+
+		li, lj := z.leftA[i], z.leftB[j]
+		fd := func(r, c int) float64 {
+			return z.fd[(r-li+1)*w+(c-lj+1)]
+		}
+
+		r, c := i, j
+		for r >= li || c >= lj {
+			switch {
+			case r >= li && fd(r, c) == fd(r-1, c)+z.deleteCost[r]:
+				r--
+			case c >= lj && fd(r, c) == fd(r, c-1)+z.insertCost[c]:
+				c--
+			case z.leftA[r] == li && z.leftB[c] == lj:
+				pairs = append(pairs, NodePair{z.nodesA[r], z.nodesB[c]})
+				r--
+				c--
+			default:
+				stack = append(stack, [2]int{r, c})
+				r, c = z.leftA[r]-1, z.leftB[c]-1
+			}
+		}
+	}
+
+	z.sortPairs(pairs)
+	return pairs
+}
+
+func (z *zhangShasha) sortPairs(pairs []NodePair) {
+	order := make(map[*Node]int, len(z.nodesA))
+	for i, n := range z.nodesA {
+		order[n] = i
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return order[pairs[i].A] < order[pairs[j].A]
+	})
+}
@@ -0,0 +1,143 @@
+package ntree_test
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+// parseLabels builds a tree from a string such as "f(d(a c(b)) e)".
+func parseLabels(s string) *ntree.Node {
+	var root, last *ntree.Node
+	parent := ntree.New(nil)
+	for _, token := range strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)) {
+		switch token {
+		case "(":
+			parent = last
+		case ")":
+			last = parent
+			parent = parent.Parent
+		default:
+			last = ntree.AppendChild(parent, ntree.New(token))
+			if root == nil {
+				root = last
+			}
+		}
+	}
+	ntree.Unlink(root)
+	return root
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"f(d(a c(b)) e)", "f(c(d(a b)) e)", 2},
+		{"a", "a", 0},
+		{"a", "b", 1},
+		{"a(b c)", "a", 2},
+		{"a", "a(b c)", 2},
+		{"a(b(c(d)))", "a(b c d)", 4},
+		{"a(b c d)", "a(d c b)", 2},
+	}
+
+	for _, c := range cases {
+		a, b := parseLabels(c.a), parseLabels(c.b)
+		if d := ntree.EditDistance(a, b, ntree.EditCosts{}); d != c.expected {
+			t.Errorf("Distance between %s and %s should be %v but got %v", c.a, c.b, c.expected, d)
+		}
+		if d := ntree.EditDistance(b, a, ntree.EditCosts{}); d != c.expected {
+			t.Errorf("Distance between %s and %s should be %v but got %v", c.b, c.a, c.expected, d)
+		}
+	}
+
+	if ntree.EditDistance(nil, parseLabels("a(b)"), ntree.EditCosts{}) != 2 || ntree.EditDistance(nil, nil, ntree.EditCosts{}) != 0 {
+		t.Error("Distance to an empty tree should be the cost of inserting every node")
+	}
+}
+
+func TestEditDistanceCosts(t *testing.T) {
+	a, b := parseLabels("a(b c)"), parseLabels("a(x c)")
+	costs := ntree.EditCosts{
+		Insert:  func(v interface{}) float64 { return 2 },
+		Delete:  func(v interface{}) float64 { return 3 },
+		Relabel: func(x, y interface{}) float64 { return 10 * math.Abs(float64(len(x.(string))-len(y.(string)))) },
+	}
+	if d := ntree.EditDistance(a, b, costs); d != 0 {
+		t.Error("Relabelling values of the same length should be free", d)
+	}
+
+	costs.Relabel = func(x, y interface{}) float64 {
+		if x == y {
+			return 0
+		}
+		return 100
+	}
+	if d := ntree.EditDistance(a, b, costs); d != 5 {
+		t.Error("Expensive relabels should become a delete and an insert", d)
+	}
+}
+
+func TestEditDistanceMapping(t *testing.T) {
+	a, b := parseLabels("f(d(a c(b)) e)"), parseLabels("f(c(d(a b)) e)")
+	d, pairs := ntree.EditDistanceMapping(a, b, ntree.EditCosts{})
+	if d != 2 {
+		t.Error("Unexpected distance", d)
+	}
+
+	// Every node of a is either matched or deleted, and every node of b matched or inserted, so the mapping
+	// has to account for the distance under unit costs.
+	cost := float64(ntree.NodeCount(a, ntree.TraverseAll) + ntree.NodeCount(b, ntree.TraverseAll) - 2*len(pairs))
+	for _, pair := range pairs {
+		if pair.A.Value != pair.B.Value {
+			cost++
+		}
+	}
+	if cost != d {
+		t.Errorf("Mapping %v costs %v instead of %v", pairs, cost, d)
+	}
+	for i := 1; i < len(pairs); i++ {
+		if ntree.LCA(pairs[i-1].A, pairs[i].A) == pairs[i-1].A {
+			t.Error("Pairs should be in post-order of a")
+		}
+	}
+}
+
+func TestEditDistanceMappingRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 50; i++ {
+		next := 1000
+		a := GenerateRandomTree(r, 1+r.Intn(25))
+		b := ntree.Copy(a[0])
+		mutateTree(r, []*ntree.Node{b}, 5, &next)
+		for n := b; n != nil; n = n.Children {
+			n.Value = r.Intn(5)
+		}
+
+		d, pairs := ntree.EditDistanceMapping(a[0], b, ntree.EditCosts{})
+		cost := float64(ntree.NodeCount(a[0], ntree.TraverseAll) + ntree.NodeCount(b, ntree.TraverseAll) - 2*len(pairs))
+		for _, pair := range pairs {
+			if pair.A.Value != pair.B.Value {
+				cost++
+			}
+		}
+		if cost != d || d != ntree.EditDistance(b, a[0], ntree.EditCosts{}) {
+			t.Fatalf("Mapping costs %v but the distance is %v", cost, d)
+		}
+	}
+}
+
+func BenchmarkEditDistance(b *testing.B) {
+	r := rand.New(rand.NewSource(4))
+	x := GenerateRandomTree(r, 2000)[0]
+	y := GenerateRandomTree(r, 2000)[0]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ntree.EditDistance(x, y, ntree.EditCosts{})
+	}
+}
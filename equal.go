@@ -0,0 +1,167 @@
+package ntree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// ValueEncoder turns a Node value into bytes that are equal exactly when the values are considered equal.
+type ValueEncoder func(v interface{}) []byte
+
+// DefaultValueEncoder encodes a value as its type and fmt %v representation.
+func DefaultValueEncoder(v interface{}) []byte {
+	return []byte(fmt.Sprintf("%T:%v", v, v))
+}
+
+// Equal reports whether the trees rooted at a and b have the same shape and equal values in the same order.  A nil
+// eq compares values with ==.
+func Equal(a, b *Node, eq func(x, y interface{}) bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if eq == nil {
+		eq = func(x, y interface{}) bool {
+			return x == y
+		}
+	}
+
+	x, y := a, b
+	for x != nil && y != nil {
+		if !eq(x.Value, y.Value) || (x.Children == nil) != (y.Children == nil) {
+			return false
+		}
+		if x != a && (x.Next == nil) != (y.Next == nil) {
+			return false
+		}
+		x, y = nextPreOrder(a, x), nextPreOrder(b, y)
+	}
+	return x == nil && y == nil
+}
+
+// Compare orders the trees rooted at a and b: first by root value, then by their lists of children compared
+// lexicographically, where a shorter list that is a prefix of the other sorts first.  A nil tree sorts before any
+// other.  A nil cmp compares values by DefaultValueEncoder.
+func Compare(a, b *Node, cmp func(x, y interface{}) int) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	if cmp == nil {
+		cmp = func(x, y interface{}) int {
+			return bytes.Compare(DefaultValueEncoder(x), DefaultValueEncoder(y))
+		}
+	}
+
+	if c := cmp(a.Value, b.Value); c != 0 {
+		return c
+	}
+	x, y := a.Children, b.Children
+	for x != nil && y != nil {
+		if c := Compare(x, y, cmp); c != 0 {
+			return c
+		}
+		x, y = x.Next, y.Next
+	}
+	switch {
+	case x == nil && y == nil:
+		return 0
+	case x == nil:
+		return -1
+	}
+	return 1
+}
+
+// Hash returns a hash of the tree rooted at root built from the encoded values.  Equal trees hash equally; when
+// ordered is false the order of siblings is ignored, so trees that are EqualUnordered hash equally too.  A nil
+// encoder uses DefaultValueEncoder.
+func Hash(root *Node, encoder ValueEncoder, ordered bool) uint64 {
+	if root == nil {
+		return 0
+	}
+	if encoder == nil {
+		encoder = DefaultValueEncoder
+	}
+
+	hashes := make(map[*Node]uint64)
+	nodes, _ := postOrder(root)
+	for _, n := range nodes[1:] {
+		var children []uint64
+		for child := n.Children; child != nil; child = child.Next {
+			children = append(children, hashes[child])
+			delete(hashes, child)
+		}
+		if !ordered {
+			sort.Slice(children, func(i, j int) bool {
+				return children[i] < children[j]
+			})
+		}
+
+		h := fnv.New64a()
+		value := encoder(n.Value)
+		var scratch [binary.MaxVarintLen64]byte
+		h.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(value)))])
+		h.Write(value)
+		for _, c := range children {
+			binary.BigEndian.PutUint64(scratch[:8], c)
+			h.Write(scratch[:8])
+		}
+		hashes[n] = h.Sum64()
+	}
+	return hashes[root]
+}
+
+// EqualUnordered reports whether the trees rooted at a and b are equal when the order of siblings is ignored.  It
+// uses the AHU algorithm: every subtree of both trees is given an id from its encoded value and the sorted ids of
+// its children, so the trees are equal exactly when their roots share an id.  A nil encoder uses
+// DefaultValueEncoder.
+func EqualUnordered(a, b *Node, encoder ValueEncoder) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if encoder == nil {
+		encoder = DefaultValueEncoder
+	}
+
+	names := make(map[string]int)
+	return canonicalID(a, encoder, names) == canonicalID(b, encoder, names)
+}
+
+func canonicalID(root *Node, encoder ValueEncoder, names map[string]int) int {
+	ids := make(map[*Node]int)
+	nodes, _ := postOrder(root)
+	for _, n := range nodes[1:] {
+		var children []int
+		for child := n.Children; child != nil; child = child.Next {
+			children = append(children, ids[child])
+			delete(ids, child)
+		}
+		sort.Ints(children)
+
+		value := encoder(n.Value)
+		var name bytes.Buffer
+		name.WriteString(strconv.Itoa(len(value)))
+		name.WriteByte(':')
+		name.Write(value)
+		for _, c := range children {
+			name.WriteByte(',')
+			name.WriteString(strconv.Itoa(c))
+		}
+
+		id, ok := names[name.String()]
+		if !ok {
+			id = len(names)
+			names[name.String()] = id
+		}
+		ids[n] = id
+	}
+	return ids[root]
+}
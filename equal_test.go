@@ -0,0 +1,143 @@
+package ntree_test
+
+import (
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func reverseChildren(n *ntree.Node) {
+	var children []*ntree.Node
+	for child := n.Children; child != nil; child = child.Next {
+		children = append(children, child)
+		reverseChildren(child)
+	}
+	for i := len(children) - 1; i >= 0; i-- {
+		ntree.Unlink(children[i])
+		ntree.AppendChild(n, children[i])
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := GenerateStringTree()
+	b := ntree.Copy(a)
+	if !ntree.Equal(a, b, nil) || !ntree.Equal(nil, nil, nil) {
+		t.Error("Copies should be equal")
+	}
+
+	b.Children.Next.Children.Value = "x"
+	if ntree.Equal(a, b, nil) {
+		t.Error("Trees with different values should not be equal")
+	}
+
+	b = ntree.Copy(a)
+	ntree.AppendChild(b.Children.Children, ntree.New("x"))
+	if ntree.Equal(a, b, nil) || ntree.Equal(b, a, nil) {
+		t.Error("Trees with different shapes should not be equal")
+	}
+
+	b = ntree.Copy(a)
+	ntree.Unlink(b.Children.Next.Children)
+	ntree.AppendChild(b.Children, ntree.New("b1"))
+	if ntree.Equal(a, b, nil) {
+		t.Error("Trees with the same pre-order values but different shapes should not be equal")
+	}
+
+	if ntree.Equal(a, nil, nil) {
+		t.Error("A tree should not equal nil")
+	}
+
+	sameLength := func(x, y interface{}) bool {
+		return len(x.(string)) == len(y.(string))
+	}
+	b = ntree.Copy(a)
+	b.Value = "ROOT"
+	if !ntree.Equal(a, b, sameLength) {
+		t.Error("Custom equality should be used")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"a(b c)", "a(b c)", 0},
+		{"a", "b", -1},
+		{"a(b)", "a", 1},
+		{"a(b c)", "a(b d)", -1},
+		{"a(b(c))", "a(b d)", 1},
+		{"a(b c)", "a(b c d)", -1},
+	}
+	for _, c := range cases {
+		a, b := parseLabels(c.a), parseLabels(c.b)
+		if got := ntree.Compare(a, b, nil); got != c.expected {
+			t.Errorf("Compare(%s, %s) should be %d but got %d", c.a, c.b, c.expected, got)
+		}
+		if got := ntree.Compare(b, a, nil); got != -c.expected {
+			t.Errorf("Compare(%s, %s) should be %d but got %d", c.b, c.a, -c.expected, got)
+		}
+	}
+
+	if ntree.Compare(nil, ntree.New(1), nil) != -1 || ntree.Compare(nil, nil, nil) != 0 {
+		t.Error("nil should sort first")
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := GenerateStringTree()
+	b := ntree.Copy(a)
+	if ntree.Hash(a, nil, true) != ntree.Hash(b, nil, true) || ntree.Hash(a, nil, false) != ntree.Hash(b, nil, false) {
+		t.Error("Equal trees should hash equally")
+	}
+
+	reverseChildren(b)
+	if ntree.Hash(a, nil, true) == ntree.Hash(b, nil, true) {
+		t.Error("Reordered trees should hash differently when order matters")
+	}
+	if ntree.Hash(a, nil, false) != ntree.Hash(b, nil, false) {
+		t.Error("Reordered trees should hash equally when order is ignored")
+	}
+
+	b.Children.Value = "changed"
+	if ntree.Hash(a, nil, false) == ntree.Hash(b, nil, false) {
+		t.Error("Trees with different values should hash differently")
+	}
+
+	if ntree.Hash(parseLabels("a(b c)"), nil, true) == ntree.Hash(parseLabels("a(b(c))"), nil, true) {
+		t.Error("Trees with different shapes should hash differently")
+	}
+}
+
+func TestEqualUnordered(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 50; i++ {
+		nodes := GenerateRandomTree(r, 1+r.Intn(40))
+		for _, n := range nodes {
+			n.Value = r.Intn(3)
+		}
+		b := ntree.Copy(nodes[0])
+		reverseChildren(b)
+
+		if !ntree.EqualUnordered(nodes[0], b, nil) {
+			t.Fatal("Reordered trees should be equal when order is ignored")
+		}
+		if ntree.Hash(nodes[0], nil, false) != ntree.Hash(b, nil, false) {
+			t.Fatal("Reordered trees should hash equally when order is ignored")
+		}
+		if ntree.Equal(nodes[0], b, nil) != (ntree.Compare(nodes[0], b, nil) == 0) {
+			t.Fatal("Equal and Compare should agree")
+		}
+	}
+
+	if ntree.EqualUnordered(parseLabels("a(b(c) b)"), parseLabels("a(b b(d))"), nil) {
+		t.Error("Trees with different leaves should not be equal")
+	}
+	if !ntree.EqualUnordered(parseLabels("a(b(c) b(d))"), parseLabels("a(b(d) b(c))"), nil) {
+		t.Error("Trees with swapped subtrees should be equal")
+	}
+	if ntree.EqualUnordered(parseLabels("a(b b)"), parseLabels("a(b)"), nil) || ntree.EqualUnordered(nil, parseLabels("a"), nil) {
+		t.Error("Trees with different sizes should not be equal")
+	}
+}
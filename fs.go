@@ -0,0 +1,279 @@
+package ntree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FSEntry describes a node as a file or directory.  It is the Value FromFS gives each node, and what an
+// FSMapper returns for NewFS.  A node with children is always a directory; Dir makes a leaf an empty directory.
+type FSEntry struct {
+	Name    string
+	Data    []byte
+	Mode    fs.FileMode
+	ModTime time.Time
+	Dir     bool
+}
+
+// FSMapper returns the file or directory a node stands for.
+type FSMapper func(n *Node) FSEntry
+
+// NodeFS exposes a tree as a read-only fs.FS.  The root node is the directory ".", and children with the same
+// name shadow later siblings.
+type NodeFS struct {
+	root   *Node
+	mapper FSMapper
+}
+
+// NewFS returns a file system over the tree rooted at root.  A nil mapper uses FSEntry or *FSEntry values
+// directly, and fmt.Sprint of any other value as the name of an empty file.
+func NewFS(root *Node, mapper FSMapper) *NodeFS {
+	if mapper == nil {
+		mapper = defaultFSMapper
+	}
+	return &NodeFS{root: root, mapper: mapper}
+}
+
+func defaultFSMapper(n *Node) FSEntry {
+	switch v := n.Value.(type) {
+	case FSEntry:
+		return v
+	case *FSEntry:
+		return *v
+	}
+	return FSEntry{Name: fmt.Sprint(n.Value)}
+}
+
+func (f *NodeFS) lookup(op, name string) (*Node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if f.root == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if name == "." {
+		return f.root, nil
+	}
+
+	n := f.root
+	for _, part := range strings.Split(name, "/") {
+		child := n.Children
+		for child != nil && f.mapper(child).Name != part {
+			child = child.Next
+		}
+		if child == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		n = child
+	}
+	return n, nil
+}
+
+func (f *NodeFS) info(n *Node) *nodeFileInfo {
+	entry := f.mapper(n)
+	if n == f.root {
+		entry.Name = "."
+	}
+	return &nodeFileInfo{node: n, entry: entry, dir: entry.Dir || n.Children != nil}
+}
+
+// Open implements fs.FS.
+func (f *NodeFS) Open(name string) (fs.File, error) {
+	n, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := f.info(n)
+	if info.dir {
+		return &nodeDir{fs: f, info: info, path: name}, nil
+	}
+	return &nodeFile{info: info, Reader: bytes.NewReader(info.entry.Data)}, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *NodeFS) Stat(name string) (fs.FileInfo, error) {
+	n, err := f.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.info(n), nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *NodeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := f.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.info(n).dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return f.entries(n), nil
+}
+
+func (f *NodeFS) entries(n *Node) []fs.DirEntry {
+	var entries []fs.DirEntry
+	for child := n.Children; child != nil; child = child.Next {
+		entries = append(entries, fs.FileInfoToDirEntry(f.info(child)))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+type nodeFileInfo struct {
+	node  *Node
+	entry FSEntry
+	dir   bool
+}
+
+func (i *nodeFileInfo) Name() string       { return i.entry.Name }
+func (i *nodeFileInfo) Size() int64        { return int64(len(i.entry.Data)) }
+func (i *nodeFileInfo) ModTime() time.Time { return i.entry.ModTime }
+func (i *nodeFileInfo) IsDir() bool        { return i.dir }
+func (i *nodeFileInfo) Sys() interface{}   { return i.node }
+
+func (i *nodeFileInfo) Mode() fs.FileMode {
+	mode := i.entry.Mode
+	if mode.Perm() == 0 {
+		mode |= 0444
+		if i.dir {
+			mode |= 0111
+		}
+	}
+	if i.dir {
+		mode |= fs.ModeDir
+	}
+	return mode
+}
+
+type nodeFile struct {
+	info *nodeFileInfo
+	*bytes.Reader
+}
+
+func (f *nodeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *nodeFile) Close() error               { return nil }
+
+type nodeDir struct {
+	fs      *NodeFS
+	info    *nodeFileInfo
+	path    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *nodeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *nodeDir) Close() error               { return nil }
+
+func (d *nodeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errors.New("is a directory")}
+}
+
+func (d *nodeDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = d.fs.entries(d.info.node)
+		if d.entries == nil {
+			d.entries = []fs.DirEntry{}
+		}
+	}
+
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+// FromFSOptions configures FromFS.  Include and Exclude are path.Match patterns tested against each entry's
+// base name: directories are only tested against Exclude, and files must match an Include pattern when any are
+// given.  MaxDepth limits how many levels below root are read, with 0 meaning no limit.  File contents are only
+// loaded when ReadData is set.
+type FromFSOptions struct {
+	Include  []string
+	Exclude  []string
+	MaxDepth int
+	ReadData bool
+}
+
+// FromFS builds a tree from the directory root of fsys.  Every node's Value is an *FSEntry.
+func FromFS(fsys fs.FS, root string, opts FromFSOptions) (*Node, error) {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var top *Node
+	dirs := make(map[string]*Node)
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != root && matchAny(opts.Exclude, d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && len(opts.Include) > 0 && !matchAny(opts.Include, d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := &FSEntry{Name: d.Name(), Mode: info.Mode(), ModTime: info.ModTime(), Dir: d.IsDir()}
+		if !d.IsDir() && opts.ReadData {
+			if entry.Data, err = fs.ReadFile(fsys, name); err != nil {
+				return err
+			}
+		}
+
+		n := New(entry)
+		if name == root {
+			top = n
+		} else {
+			AppendChild(dirs[path.Dir(name)], n)
+		}
+
+		if d.IsDir() {
+			dirs[name] = n
+			if opts.MaxDepth > 0 && Depth(n) > opts.MaxDepth {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
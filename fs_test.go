@@ -0,0 +1,113 @@
+package ntree_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func GenerateFSTree() *ntree.Node {
+	root := ntree.New(&ntree.FSEntry{Name: "root"})
+	docs := ntree.AppendChild(root, ntree.New(&ntree.FSEntry{Name: "docs"}))
+	ntree.AppendChild(docs, ntree.New(&ntree.FSEntry{Name: "readme.md", Data: []byte("# readme")}))
+	ntree.AppendChild(docs, ntree.New(&ntree.FSEntry{Name: "guide.txt", Data: []byte("guide")}))
+	ntree.AppendChild(root, ntree.New(&ntree.FSEntry{Name: "main.go", Data: []byte("package main")}))
+	ntree.AppendChild(root, ntree.New(&ntree.FSEntry{Name: "empty", Dir: true}))
+	return root
+}
+
+func TestNodeFS(t *testing.T) {
+	fsys := ntree.NewFS(GenerateFSTree(), nil)
+	if err := fstest.TestFS(fsys, "docs/readme.md", "docs/guide.txt", "main.go", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "docs/guide.txt")
+	if err != nil || string(data) != "guide" {
+		t.Error("Unexpected file content", string(data), err)
+	}
+
+	var walked []string
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		walked = append(walked, name)
+		return err
+	})
+	expected := []string{".", "docs", "docs/guide.txt", "docs/readme.md", "empty", "main.go"}
+	if len(walked) != len(expected) {
+		t.Fatal("Unexpected walk", walked)
+	}
+	for i := range expected {
+		if walked[i] != expected[i] {
+			t.Errorf("Expected %s but got %s", expected[i], walked[i])
+		}
+	}
+
+	if _, err := fsys.Open("missing"); err == nil {
+		t.Error("Opening a missing file should fail")
+	}
+	if _, err := fsys.Open("/docs"); err == nil {
+		t.Error("Opening an invalid path should fail")
+	}
+	if _, err := fsys.ReadDir("main.go"); err == nil {
+		t.Error("Reading a file as a directory should fail")
+	}
+
+	info, err := fsys.Stat("docs")
+	if err != nil || !info.IsDir() || info.Sys().(*ntree.Node).Value.(*ntree.FSEntry).Name != "docs" {
+		t.Error("Stat should describe the directory node", err)
+	}
+}
+
+func TestNodeFSMapper(t *testing.T) {
+	fsys := ntree.NewFS(GenerateStringTree(), func(n *ntree.Node) ntree.FSEntry {
+		return ntree.FSEntry{Name: n.Value.(string) + ".n", Data: []byte(n.Value.(string))}
+	})
+	if err := fstest.TestFS(fsys, "a.n/a1.n", "b.n/b1.n/b1x.n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"src/main.go":           {Data: []byte("package main")},
+		"src/util/util.go":      {Data: []byte("package util")},
+		"src/util/util_test.go": {Data: []byte("package util")},
+		"src/util/deep/x.go":    {Data: []byte("package deep")},
+		"src/README":            {Data: []byte("readme")},
+		"src/.git/config":       {Data: []byte("git")},
+	}
+
+	root, err := ntree.FromFS(mapFS, "src", ntree.FromFSOptions{ReadData: true})
+	if err != nil {
+		t.Fatal("FromFS failed:", err)
+	}
+	if ntree.NodeCount(root, ntree.TraverseAll) != 10 {
+		t.Error("Unexpected node count", ntree.NodeCount(root, ntree.TraverseAll))
+	}
+	if err := fstest.TestFS(ntree.NewFS(root, nil), "main.go", "util/deep/x.go", ".git/config"); err != nil {
+		t.Error(err)
+	}
+
+	root, err = ntree.FromFS(mapFS, "src", ntree.FromFSOptions{Include: []string{"*.go"}, Exclude: []string{".*", "*_test.go"}, MaxDepth: 2})
+	if err != nil {
+		t.Fatal("FromFS failed:", err)
+	}
+	if err := fstest.TestFS(ntree.NewFS(root, nil), "main.go", "util/util.go", "util/deep"); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.Stat(ntree.NewFS(root, nil), "util/deep/x.go"); err == nil {
+		t.Error("MaxDepth should stop the walk")
+	}
+	if data, _ := fs.ReadFile(ntree.NewFS(root, nil), "main.go"); len(data) != 0 {
+		t.Error("Data should only be read with ReadData")
+	}
+
+	if _, err := ntree.FromFS(mapFS, "missing", ntree.FromFSOptions{}); err == nil {
+		t.Error("FromFS should fail for a missing root")
+	}
+	if _, err := ntree.FromFS(mapFS, "src", ntree.FromFSOptions{Include: []string{"["}}); err == nil {
+		t.Error("FromFS should fail for a bad pattern")
+	}
+}
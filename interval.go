@@ -0,0 +1,170 @@
+package ntree
+
+// IntervalIndex numbers the nodes of a tree in pre-order so that the subtree of a node is the interval from its
+// entry number up to its exit number, exclusive.  Ancestry checks, subtree sizes and lookups by rank then take
+// O(1).  Like LCAIndex, it answers ErrStaleIndex after any change made to the tree with this package's functions.
+type IntervalIndex struct {
+	treeSnapshot
+
+	nodes []*Node
+	entry map[*Node]int
+	sizes []int
+}
+
+// NewIntervalIndex builds an IntervalIndex over the tree rooted at root in one pre-order pass.
+func NewIntervalIndex(root *Node) *IntervalIndex {
+	if root == nil {
+		return nil
+	}
+
+	x := &IntervalIndex{treeSnapshot: newTreeSnapshot(root), entry: make(map[*Node]int)}
+	for n := root; n != nil; n = nextPreOrder(root, n) {
+		x.entry[n] = len(x.nodes)
+		x.nodes = append(x.nodes, n)
+	}
+
+	// Children come after their parent in pre-order, so a backwards sweep finishes every subtree before adding it
+	// to its parent.
+	x.sizes = make([]int, len(x.nodes))
+	for i := len(x.nodes) - 1; i >= 0; i-- {
+		x.sizes[i]++
+		if n := x.nodes[i]; n != root {
+			x.sizes[x.entry[n.Parent]] += x.sizes[i]
+		}
+	}
+	return x
+}
+
+func (x *IntervalIndex) rank(n *Node) (int, error) {
+	if x.stale() {
+		return 0, ErrStaleIndex
+	}
+	i, ok := x.entry[n]
+	if !ok {
+		return 0, ErrNotIndexed
+	}
+	return i, nil
+}
+
+// Len returns the number of indexed nodes.
+func (x *IntervalIndex) Len() int {
+	return len(x.nodes)
+}
+
+// Interval returns the entry and exit numbers of n.  The nodes of its subtree are exactly those whose entry
+// numbers fall in [entry, exit).
+func (x *IntervalIndex) Interval(n *Node) (entry, exit int, err error) {
+	i, err := x.rank(n)
+	if err != nil {
+		return 0, 0, err
+	}
+	return i, i + x.sizes[i], nil
+}
+
+// Size returns the number of nodes in the subtree of n, counting n itself.
+func (x *IntervalIndex) Size(n *Node) (int, error) {
+	i, err := x.rank(n)
+	if err != nil {
+		return 0, err
+	}
+	return x.sizes[i], nil
+}
+
+// IsAncestor reports whether a is a proper ancestor of b.
+func (x *IntervalIndex) IsAncestor(a, b *Node) (bool, error) {
+	i, err := x.rank(a)
+	if err != nil {
+		return false, err
+	}
+	j, err := x.rank(b)
+	if err != nil {
+		return false, err
+	}
+	return i < j && j < i+x.sizes[i], nil
+}
+
+// AtRank returns the node with pre-order entry number rank.
+func (x *IntervalIndex) AtRank(rank int) (*Node, error) {
+	if x.stale() {
+		return nil, ErrStaleIndex
+	}
+	if rank < 0 || rank >= len(x.nodes) {
+		return nil, ErrInvalidPosition
+	}
+	return x.nodes[rank], nil
+}
+
+// SubtreeSum keeps a number for every node of an IntervalIndex in a Fenwick tree keyed on the entry numbers, so
+// that the numbers of any subtree can be summed, and single numbers changed, in O(log n).
+type SubtreeSum struct {
+	index  *IntervalIndex
+	values []float64
+	tree   []float64
+}
+
+// NewSubtreeSum returns a SubtreeSum over the nodes of x, starting each node at weight(n).
+func (x *IntervalIndex) NewSubtreeSum(weight func(n *Node) float64) *SubtreeSum {
+	if weight == nil {
+		return nil
+	}
+
+	s := &SubtreeSum{index: x, values: make([]float64, len(x.nodes)), tree: make([]float64, len(x.nodes)+1)}
+	for i, n := range x.nodes {
+		s.values[i] = weight(n)
+		s.tree[i+1] += s.values[i]
+		if j := i + 1 + (i+1)&-(i+1); j < len(s.tree) {
+			s.tree[j] += s.tree[i+1]
+		}
+	}
+	return s
+}
+
+// prefix returns the sum of the numbers of the first i nodes in pre-order.
+func (s *SubtreeSum) prefix(i int) float64 {
+	sum := 0.0
+	for ; i > 0; i -= i & -i {
+		sum += s.tree[i]
+	}
+	return sum
+}
+
+// Sum returns the sum of the numbers in the subtree of n.
+func (s *SubtreeSum) Sum(n *Node) (float64, error) {
+	entry, exit, err := s.index.Interval(n)
+	if err != nil {
+		return 0, err
+	}
+	return s.prefix(exit) - s.prefix(entry), nil
+}
+
+// Get returns the number of n.
+func (s *SubtreeSum) Get(n *Node) (float64, error) {
+	i, err := s.index.rank(n)
+	if err != nil {
+		return 0, err
+	}
+	return s.values[i], nil
+}
+
+// Add adds delta to the number of n.
+func (s *SubtreeSum) Add(n *Node, delta float64) error {
+	i, err := s.index.rank(n)
+	if err != nil {
+		return err
+	}
+
+	s.values[i] += delta
+	for j := i + 1; j < len(s.tree); j += j & -j {
+		s.tree[j] += delta
+	}
+	return nil
+}
+
+// Set sets the number of n to v.
+func (s *SubtreeSum) Set(n *Node, v float64) error {
+	current, err := s.Get(n)
+	if err != nil {
+		return err
+	}
+	return s.Add(n, v-current)
+}
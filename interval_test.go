@@ -0,0 +1,105 @@
+package ntree_test
+
+import (
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func TestIntervalIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	nodes := GenerateRandomTree(r, 500)
+	index := ntree.NewIntervalIndex(nodes[0])
+	if index.Len() != len(nodes) {
+		t.Fatal("Expected", len(nodes), "indexed nodes but got", index.Len())
+	}
+
+	for _, n := range nodes {
+		size, err := index.Size(n)
+		if err != nil || size != ntree.NodeCount(n, ntree.TraverseAll) {
+			t.Fatal("Wrong subtree size for", n.Value, err)
+		}
+		entry, exit, _ := index.Interval(n)
+		if exit-entry != size {
+			t.Fatal("Exit minus entry should be the subtree size")
+		}
+		if at, err := index.AtRank(entry); at != n || err != nil {
+			t.Fatal("AtRank should return the node with that entry number")
+		}
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := nodes[r.Intn(len(nodes))], nodes[r.Intn(len(nodes))]
+		if ok, err := index.IsAncestor(a, b); err != nil || ok != isAncestor(a, b) {
+			t.Fatal("Wrong ancestry for", a.Value, b.Value, err)
+		}
+	}
+
+	if _, err := index.AtRank(len(nodes)); err != ntree.ErrInvalidPosition {
+		t.Error("AtRank past the end should fail")
+	}
+	if _, err := index.Size(ntree.New(0)); err != ntree.ErrNotIndexed {
+		t.Error("Nodes outside the index should be reported")
+	}
+
+	ntree.Unlink(nodes[len(nodes)-1])
+	if _, err := index.IsAncestor(nodes[0], nodes[1]); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after Unlink", err)
+	}
+	if _, err := index.AtRank(0); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after Unlink", err)
+	}
+
+	// Changes made while the indexed tree is part of another one must still be noticed once it is detached.
+	tree, a, b := ntree.New(0), ntree.New(1), ntree.New(2)
+	ntree.AppendChild(tree, a)
+	ntree.AppendChild(tree, b)
+	c := ntree.AppendChild(a, ntree.New(3))
+	index = ntree.NewIntervalIndex(tree)
+	sums := index.NewSubtreeSum(func(n *ntree.Node) float64 {
+		return 1
+	})
+	ntree.AppendChild(ntree.New(4), tree)
+	ntree.Move(c, b, -1)
+	ntree.Unlink(tree)
+	if _, err := index.IsAncestor(b, c); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after a change made while its tree was attached elsewhere", err)
+	}
+	if _, err := sums.Sum(b); err != ntree.ErrStaleIndex {
+		t.Error("Subtree sums should be stale after a change made while the tree was attached elsewhere", err)
+	}
+}
+
+func TestSubtreeSum(t *testing.T) {
+	r := rand.New(rand.NewSource(17))
+	nodes := GenerateRandomTree(r, 300)
+	index := ntree.NewIntervalIndex(nodes[0])
+	sums := index.NewSubtreeSum(func(n *ntree.Node) float64 {
+		return float64(n.Value.(int))
+	})
+
+	for i := 0; i < 1000; i++ {
+		n := nodes[r.Intn(len(nodes))]
+		if r.Intn(2) == 0 {
+			v := r.Intn(1000)
+			ntree.SetValue(n, v)
+			if err := sums.Set(n, float64(v)); err != nil {
+				t.Fatal("Set failed:", err)
+			}
+			continue
+		}
+
+		sum, err := sums.Sum(n)
+		if err != nil || int(sum) != freshSum(n) {
+			t.Fatalf("Subtree sum %v differs from fresh sum %v", sum, freshSum(n))
+		}
+	}
+
+	if err := sums.Add(nodes[0], 2.5); err != nil {
+		t.Fatal("Add failed:", err)
+	}
+	if v, _ := sums.Get(nodes[0]); v != float64(nodes[0].Value.(int))+2.5 {
+		t.Error("Add should change a single number, got", v)
+	}
+}
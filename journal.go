@@ -0,0 +1,304 @@
+package ntree
+
+import (
+	"errors"
+)
+
+var (
+	ErrNothingToUndo   = errors.New("ntree: nothing to undo")
+	ErrNothingToRedo   = errors.New("ntree: nothing to redo")
+	ErrNoTransaction   = errors.New("ntree: no open transaction")
+	ErrTransactionOpen = errors.New("ntree: transaction still open")
+)
+
+// Journal makes tree changes through its methods and records them so that they can be undone and redone.  Changes
+// are undone in reverse order, so the tree must not be changed behind the journal's back in between.
+type Journal struct {
+	limit  int
+	done   []journalEntry
+	undone []journalEntry
+
+	open  *journalEntry
+	depth int
+}
+
+// journalEntry is one undoable step: a single change or a whole transaction.
+type journalEntry struct {
+	name    string
+	changes []journalChange
+}
+
+// journalChange records the nodes one operation moved or changed the value of.  Its ops happened together, so
+// their recorded siblings may be each other.
+type journalChange []journalOp
+
+// journalOp records where a node was, and what value it had, before and after a change.  Structural changes are
+// all moves from one place to another, where a nil parent means the node was a root.
+type journalOp struct {
+	n                    *Node
+	fromParent, fromNext *Node
+	toParent, toNext     *Node
+	oldValue, newValue   interface{}
+	setsValue            bool
+}
+
+// NewJournal returns a journal that keeps at most limit undoable steps, or any number if limit is 0 or less.
+func NewJournal(limit int) *Journal {
+	return &Journal{limit: limit}
+}
+
+// place unlinks the nodes of c and puts each back before or after the change, inserting a node only once the
+// sibling it goes before is in place.
+func (c journalChange) place(after bool) {
+	pending := make(map[*Node]bool)
+	for _, op := range c {
+		Unlink(op.n)
+		pending[op.n] = true
+	}
+	for len(pending) > 0 {
+		for _, op := range c {
+			parent, next := op.fromParent, op.fromNext
+			if after {
+				parent, next = op.toParent, op.toNext
+			}
+			if !pending[op.n] || pending[next] {
+				continue
+			}
+			if parent != nil {
+				InsertBefore(parent, next, op.n)
+			}
+			delete(pending, op.n)
+		}
+	}
+}
+
+func (c journalChange) undo() {
+	c.place(false)
+	for _, op := range c {
+		if op.setsValue {
+			SetValue(op.n, op.oldValue)
+		}
+	}
+}
+
+func (c journalChange) redo() {
+	c.place(true)
+	for _, op := range c {
+		if op.setsValue {
+			SetValue(op.n, op.newValue)
+		}
+	}
+}
+
+// record runs change, which may only move nodes or change the value of the single node, and records it.  Nodes
+// left where they were are not recorded.
+func (j *Journal) record(nodes []*Node, setsValue bool, change func()) {
+	ops := make([]journalOp, len(nodes))
+	for i, n := range nodes {
+		ops[i] = journalOp{n: n, fromParent: n.Parent, fromNext: n.Next, oldValue: n.Value, setsValue: setsValue}
+	}
+	change()
+
+	var c journalChange
+	for _, op := range ops {
+		op.toParent, op.toNext, op.newValue = op.n.Parent, op.n.Next, op.n.Value
+		if setsValue || op.fromParent != op.toParent || op.fromNext != op.toNext {
+			c = append(c, op)
+		}
+	}
+	if len(c) == 0 {
+		return
+	}
+
+	j.undone = nil
+	if j.open != nil {
+		j.open.changes = append(j.open.changes, c)
+		return
+	}
+	j.push(journalEntry{changes: []journalChange{c}})
+}
+
+func (j *Journal) push(e journalEntry) {
+	j.done = append(j.done, e)
+	if j.limit > 0 && len(j.done) > j.limit {
+		j.done = append(j.done[:0], j.done[len(j.done)-j.limit:]...)
+	}
+}
+
+// AppendChild is like AppendChild but recorded.
+func (j *Journal) AppendChild(parent, n *Node) *Node {
+	var result *Node
+	if n != nil {
+		j.record([]*Node{n}, false, func() {
+			result = AppendChild(parent, n)
+		})
+	}
+	return result
+}
+
+// InsertBefore is like InsertBefore but recorded.
+func (j *Journal) InsertBefore(parent, sibling, n *Node) *Node {
+	var result *Node
+	if n != nil {
+		j.record([]*Node{n}, false, func() {
+			result = InsertBefore(parent, sibling, n)
+		})
+	}
+	return result
+}
+
+// Unlink is like Unlink but recorded.
+func (j *Journal) Unlink(n *Node) {
+	if n != nil {
+		j.record([]*Node{n}, false, func() {
+			Unlink(n)
+		})
+	}
+}
+
+// Move is like Move but recorded.
+func (j *Journal) Move(n, newParent *Node, position int) error {
+	if n == nil {
+		return ErrInvalidNode
+	}
+
+	var err error
+	j.record([]*Node{n}, false, func() {
+		err = Move(n, newParent, position)
+	})
+	return err
+}
+
+// SwapSiblings is like SwapSiblings but recorded as a single change.
+func (j *Journal) SwapSiblings(a, b *Node) error {
+	if a == nil || b == nil {
+		return ErrInvalidNode
+	}
+
+	var err error
+	j.record([]*Node{a, b}, false, func() {
+		err = SwapSiblings(a, b)
+	})
+	return err
+}
+
+// SwapSubtrees is like SwapSubtrees but recorded as a single change.
+func (j *Journal) SwapSubtrees(a, b *Node) error {
+	if a == nil || b == nil {
+		return ErrInvalidNode
+	}
+
+	var err error
+	j.record([]*Node{a, b}, false, func() {
+		err = SwapSubtrees(a, b)
+	})
+	return err
+}
+
+// ReplaceNode is like ReplaceNode but recorded as a single change.
+func (j *Journal) ReplaceNode(old, replacement *Node) error {
+	if old == nil || replacement == nil {
+		return ErrInvalidNode
+	}
+
+	var err error
+	j.record([]*Node{old, replacement}, false, func() {
+		err = ReplaceNode(old, replacement)
+	})
+	return err
+}
+
+// SetValue sets the value of n and records the change.
+func (j *Journal) SetValue(n *Node, v interface{}) {
+	if n != nil {
+		j.record([]*Node{n}, true, func() {
+			SetValue(n, v)
+		})
+	}
+}
+
+// Begin starts a transaction called name, which is undone and redone as a single step.  Nested transactions are
+// part of the outermost one.
+func (j *Journal) Begin(name string) {
+	j.depth++
+	if j.open == nil {
+		j.open = &journalEntry{name: name}
+	}
+}
+
+// Commit ends the innermost transaction.  Ending the outermost one makes it an undoable step, unless it is empty.
+func (j *Journal) Commit() error {
+	if j.open == nil {
+		return ErrNoTransaction
+	}
+
+	j.depth--
+	if j.depth > 0 {
+		return nil
+	}
+	if len(j.open.changes) > 0 {
+		j.push(*j.open)
+	}
+	j.open = nil
+	return nil
+}
+
+// Rollback undoes and forgets every change of the open transaction, including nested ones, and closes it.
+func (j *Journal) Rollback() error {
+	if j.open == nil {
+		return ErrNoTransaction
+	}
+
+	for i := len(j.open.changes) - 1; i >= 0; i-- {
+		j.open.changes[i].undo()
+	}
+	j.open = nil
+	j.depth = 0
+	return nil
+}
+
+// Undo undoes the latest step and returns its name, which is empty for steps outside transactions.
+func (j *Journal) Undo() (string, error) {
+	if j.open != nil {
+		return "", ErrTransactionOpen
+	}
+	if len(j.done) == 0 {
+		return "", ErrNothingToUndo
+	}
+
+	e := j.done[len(j.done)-1]
+	j.done = j.done[:len(j.done)-1]
+	for i := len(e.changes) - 1; i >= 0; i-- {
+		e.changes[i].undo()
+	}
+	j.undone = append(j.undone, e)
+	return e.name, nil
+}
+
+// Redo redoes the latest undone step and returns its name.  Recording a new change forgets the undone steps.
+func (j *Journal) Redo() (string, error) {
+	if j.open != nil {
+		return "", ErrTransactionOpen
+	}
+	if len(j.undone) == 0 {
+		return "", ErrNothingToRedo
+	}
+
+	e := j.undone[len(j.undone)-1]
+	j.undone = j.undone[:len(j.undone)-1]
+	for _, c := range e.changes {
+		c.redo()
+	}
+	j.push(e)
+	return e.name, nil
+}
+
+// CanUndo reports whether there is a step to undo.
+func (j *Journal) CanUndo() bool {
+	return len(j.done) > 0
+}
+
+// CanRedo reports whether there is a step to redo.
+func (j *Journal) CanRedo() bool {
+	return len(j.undone) > 0
+}
@@ -0,0 +1,147 @@
+package ntree_test
+
+import (
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func randomJournalOps(r *rand.Rand, j *ntree.Journal, nodes []*ntree.Node, count int, next *int) []*ntree.Node {
+	for i := 0; i < count; i++ {
+		n := nodes[r.Intn(len(nodes))]
+		other := nodes[r.Intn(len(nodes))]
+		switch r.Intn(9) {
+		case 0:
+			*next++
+			nodes = append(nodes, j.AppendChild(n, ntree.New(*next)))
+		case 1:
+			if n.Parent != nil {
+				*next++
+				nodes = append(nodes, j.InsertBefore(n.Parent, n, ntree.New(*next)))
+			}
+		case 2:
+			j.Unlink(n)
+		case 3:
+			j.Move(n, other, r.Intn(3)-1)
+		case 4:
+			*next++
+			j.SetValue(n, *next)
+		case 6:
+			if n.Parent != nil {
+				j.SwapSiblings(n, n.Parent.Children)
+			}
+		case 7:
+			j.SwapSubtrees(n, other)
+		case 8:
+			*next++
+			if replacement := ntree.New(*next); j.ReplaceNode(n, replacement) == nil {
+				nodes = append(nodes, replacement)
+			}
+		case 5:
+			j.Begin("group")
+			nodes = randomJournalOps(r, j, nodes, r.Intn(4), next)
+			if r.Intn(4) == 0 {
+				j.Rollback()
+			} else {
+				j.Commit()
+			}
+		}
+	}
+	return nodes
+}
+
+func TestJournalRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for round := 0; round < 50; round++ {
+		nodes := GenerateRandomTree(r, 30)
+		root := nodes[0]
+		original := ntree.Copy(root)
+		parents := make(map[*ntree.Node]*ntree.Node)
+		for _, n := range nodes {
+			parents[n] = n.Parent
+		}
+
+		j := ntree.NewJournal(0)
+		next := len(nodes)
+		nodes = randomJournalOps(r, j, nodes, 100, &next)
+		final := ntree.Copy(root)
+
+		for j.CanUndo() {
+			if _, err := j.Undo(); err != nil {
+				t.Fatal("Undo failed:", err)
+			}
+		}
+		if !ntree.Equal(root, original, nil) {
+			t.Fatal("Undoing every change should restore the original tree")
+		}
+		for n, parent := range parents {
+			if n.Parent != parent {
+				t.Fatal("Undoing every change should restore every parent")
+			}
+		}
+
+		for j.CanRedo() {
+			if _, err := j.Redo(); err != nil {
+				t.Fatal("Redo failed:", err)
+			}
+		}
+		if !ntree.Equal(root, final, nil) {
+			t.Fatal("Redoing every change should restore the final tree")
+		}
+	}
+}
+
+func TestJournalTransactions(t *testing.T) {
+	root := GenerateStringTree()
+	original := ntree.Copy(root)
+	j := ntree.NewJournal(2)
+
+	j.Begin("rename")
+	j.SetValue(root.Children, "x")
+	j.Begin("nested")
+	j.SetValue(root.Children.Next, "y")
+	j.Commit()
+	if _, err := j.Undo(); err != ntree.ErrTransactionOpen {
+		t.Error("Undo inside a transaction should fail")
+	}
+	j.Commit()
+	checkChildren(t, root, "x", "y", "")
+
+	j.Begin("rolled back")
+	j.Unlink(root.Children)
+	j.Rollback()
+	checkChildren(t, root, "x", "y", "")
+
+	j.Unlink(root.Children.Next.Next)
+	if name, err := j.Undo(); name != "" || err != nil {
+		t.Error("Unexpected undo result", name, err)
+	}
+	if name, err := j.Undo(); name != "rename" || err != nil {
+		t.Error("Unexpected undo result", name, err)
+	}
+	if !ntree.Equal(root, original, nil) {
+		t.Error("Undo should restore the original tree")
+	}
+
+	if name, err := j.Redo(); name != "rename" || err != nil {
+		t.Error("Unexpected redo result", name, err)
+	}
+	j.SetValue(root, "new")
+	if _, err := j.Redo(); err != ntree.ErrNothingToRedo {
+		t.Error("A new change should forget undone steps")
+	}
+
+	j.SetValue(root, "newer")
+	j.Undo()
+	j.Undo()
+	if _, err := j.Undo(); err != ntree.ErrNothingToUndo {
+		t.Error("History limit should drop old steps")
+	}
+	if root.Value != "root" || root.Children.Value != "x" {
+		t.Error("Unexpected tree after undoing to the history limit")
+	}
+	if j.Commit() != ntree.ErrNoTransaction || j.Rollback() != ntree.ErrNoTransaction {
+		t.Error("Commit and Rollback need an open transaction")
+	}
+}
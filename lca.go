@@ -0,0 +1,167 @@
+package ntree
+
+import (
+	"errors"
+)
+
+var (
+	ErrStaleIndex = errors.New("ntree: tree changed since the index was built")
+	ErrNotIndexed = errors.New("ntree: node is not in the index")
+)
+
+// LCA returns the lowest common ancestor of a and b by walking parent pointers, or nil if they are not in the
+// same tree.  A node counts as its own ancestor.
+func LCA(a, b *Node) *Node {
+	if a == nil || b == nil {
+		return nil
+	}
+
+	depthA, depthB := Depth(a), Depth(b)
+	for ; depthA > depthB; depthA-- {
+		a = a.Parent
+	}
+	for ; depthB > depthA; depthB-- {
+		b = b.Parent
+	}
+	for a != b {
+		a, b = a.Parent, b.Parent
+	}
+	return a
+}
+
+// LCAIndex answers lowest common ancestor, distance and ancestry queries over a tree in O(1) after an O(n log n)
+// Euler tour and sparse table preprocessing.  Queries return ErrStaleIndex once the tree has been changed through
+// AppendChild, Unlink or the other mutation functions of this package.
+type LCAIndex struct {
+	treeSnapshot
+
+	euler  []*Node
+	depths []int
+	first  map[*Node]int
+	last   map[*Node]int
+	sparse [][]int32
+}
+
+// NewLCAIndex builds an LCAIndex over the tree rooted at root.
+func NewLCAIndex(root *Node) *LCAIndex {
+	if root == nil {
+		return nil
+	}
+
+	x := &LCAIndex{treeSnapshot: newTreeSnapshot(root), first: make(map[*Node]int), last: make(map[*Node]int)}
+
+	depth := 0
+	visit := func(n *Node) {
+		if _, ok := x.first[n]; !ok {
+			x.first[n] = len(x.euler)
+		}
+		x.last[n] = len(x.euler)
+		x.euler = append(x.euler, n)
+		x.depths = append(x.depths, depth)
+	}
+
+	n := root
+	visit(n)
+	for {
+		if n.Children != nil {
+			n = n.Children
+			depth++
+			visit(n)
+			continue
+		}
+
+		for n != root && n.Next == nil {
+			n = n.Parent
+			depth--
+			visit(n)
+		}
+		if n == root {
+			break
+		}
+		depth--
+		visit(n.Parent)
+		n = n.Next
+		depth++
+		visit(n)
+	}
+
+	x.buildSparse()
+	return x
+}
+
+func (x *LCAIndex) buildSparse() {
+	size := len(x.euler)
+	level := make([]int32, size)
+	for i := range level {
+		level[i] = int32(i)
+	}
+	x.sparse = [][]int32{level}
+
+	for width := 2; width <= size; width *= 2 {
+		prev := x.sparse[len(x.sparse)-1]
+		level := make([]int32, size-width+1)
+		for i := range level {
+			level[i] = x.minDepth(prev[i], prev[i+width/2])
+		}
+		x.sparse = append(x.sparse, level)
+	}
+}
+
+func (x *LCAIndex) minDepth(i, j int32) int32 {
+	if x.depths[j] < x.depths[i] {
+		return j
+	}
+	return i
+}
+
+func (x *LCAIndex) check(a, b *Node) (int, int, error) {
+	if x.stale() {
+		return 0, 0, ErrStaleIndex
+	}
+
+	i, okA := x.first[a]
+	j, okB := x.first[b]
+	if !okA || !okB {
+		return 0, 0, ErrNotIndexed
+	}
+	return i, j, nil
+}
+
+// LCA returns the lowest common ancestor of a and b.
+func (x *LCAIndex) LCA(a, b *Node) (*Node, error) {
+	i, j, err := x.check(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return x.euler[x.query(i, j)], nil
+}
+
+func (x *LCAIndex) query(i, j int) int {
+	if i > j {
+		i, j = j, i
+	}
+
+	k := 0
+	for 2<<uint(k) <= j-i+1 {
+		k++
+	}
+	return int(x.minDepth(x.sparse[k][i], x.sparse[k][j-(1<<uint(k))+1]))
+}
+
+// Distance returns the number of edges on the path between a and b.
+func (x *LCAIndex) Distance(a, b *Node) (int, error) {
+	i, j, err := x.check(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return x.depths[i] + x.depths[j] - 2*x.depths[x.query(i, j)], nil
+}
+
+// IsAncestor reports whether a is a proper ancestor of b.
+func (x *LCAIndex) IsAncestor(a, b *Node) (bool, error) {
+	i, j, err := x.check(a, b)
+	if err != nil {
+		return false, err
+	}
+	return i < j && x.last[b] <= x.last[a], nil
+}
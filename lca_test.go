@@ -0,0 +1,132 @@
+package ntree_test
+
+import (
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func GenerateRandomTree(r *rand.Rand, size int) []*ntree.Node {
+	nodes := []*ntree.Node{ntree.New(0)}
+	for i := 1; i < size; i++ {
+		n := ntree.New(i)
+		ntree.AppendChild(nodes[r.Intn(len(nodes))], n)
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func isAncestor(a, b *ntree.Node) bool {
+	for b = b.Parent; b != nil; b = b.Parent {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLCA(t *testing.T) {
+	nodes := GenerateTree()
+
+	if ntree.LCA(nodes["a_1_1"], nodes["a_1_3"]) != nodes["a_1"] {
+		t.Error("LCA of siblings should be their parent")
+	}
+	if ntree.LCA(nodes["a_1_1"], nodes["a_2_2"]) != nodes["root"] {
+		t.Error("LCA of cousins should be the root")
+	}
+	if ntree.LCA(nodes["a_1"], nodes["a_1_2"]) != nodes["a_1"] {
+		t.Error("LCA of a node and its descendant should be the node")
+	}
+	if ntree.LCA(nodes["a_1"], ntree.New(1)) != nil || ntree.LCA(nil, nodes["a_1"]) != nil {
+		t.Error("LCA of nodes in different trees should be nil")
+	}
+}
+
+func TestLCAIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	nodes := GenerateRandomTree(r, 500)
+	index := ntree.NewLCAIndex(nodes[0])
+
+	for i := 0; i < 2000; i++ {
+		a, b := nodes[r.Intn(len(nodes))], nodes[r.Intn(len(nodes))]
+
+		lca, err := index.LCA(a, b)
+		if err != nil || lca != ntree.LCA(a, b) {
+			t.Fatal("Index LCA does not match LCA", err)
+		}
+
+		distance, err := index.Distance(a, b)
+		expected := ntree.Depth(a) + ntree.Depth(b) - 2*ntree.Depth(lca)
+		if err != nil || distance != expected {
+			t.Fatalf("Expected distance %d but got %d", expected, distance)
+		}
+
+		ancestor, err := index.IsAncestor(a, b)
+		if err != nil || ancestor != isAncestor(a, b) {
+			t.Fatal("Index IsAncestor does not match parent pointers", err)
+		}
+	}
+
+	if _, err := index.LCA(nodes[1], ntree.New(0)); err != ntree.ErrNotIndexed {
+		t.Error("Nodes outside the index should be reported", err)
+	}
+
+	single := ntree.NewLCAIndex(nodes[0].Children.Children)
+	if single == nil {
+		t.Fatal("Index over a subtree should be built")
+	}
+	if ntree.NewLCAIndex(nil) != nil {
+		t.Error("Index over nil should be nil")
+	}
+}
+
+func TestLCAIndexStale(t *testing.T) {
+	nodes := GenerateTree()
+	index := ntree.NewLCAIndex(nodes["root"])
+	sub := ntree.NewLCAIndex(nodes["a_1"])
+
+	ntree.AppendChild(nodes["a_2_2"], ntree.New(1))
+	if _, err := index.LCA(nodes["a_1"], nodes["a_2"]); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after AppendChild", err)
+	}
+	if _, err := sub.Distance(nodes["a_1_1"], nodes["a_1_2"]); err != ntree.ErrStaleIndex {
+		t.Error("Subtree index should be stale after a change elsewhere in the tree", err)
+	}
+
+	index = ntree.NewLCAIndex(nodes["root"])
+	ntree.Unlink(nodes["a_1_2"])
+	if _, err := index.IsAncestor(nodes["a_1"], nodes["a_1_1"]); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after Unlink", err)
+	}
+
+	detached := ntree.NewLCAIndex(nodes["a_1_2"])
+	ntree.AppendChild(nodes["a_2"], nodes["a_1_2"])
+	if _, err := detached.LCA(nodes["a_1_2"], nodes["a_1_2"]); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after its root is attached to another tree", err)
+	}
+
+	// Changes made while the indexed tree is part of another one must still be noticed once it is detached.
+	tree, a, b := ntree.New("t"), ntree.New("a"), ntree.New("b")
+	ntree.AppendChild(tree, a)
+	ntree.AppendChild(tree, b)
+	c := ntree.AppendChild(a, ntree.New("c"))
+	index = ntree.NewLCAIndex(tree)
+	ntree.AppendChild(ntree.New("p"), tree)
+	ntree.Move(c, b, -1)
+	ntree.Unlink(tree)
+	if _, err := index.LCA(c, b); err != ntree.ErrStaleIndex {
+		t.Error("Index should be stale after a change made while its tree was attached elsewhere", err)
+	}
+}
+
+func BenchmarkLCAIndex(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	nodes := GenerateRandomTree(r, 100000)
+	index := ntree.NewLCAIndex(nodes[0])
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.LCA(nodes[r.Intn(len(nodes))], nodes[r.Intn(len(nodes))])
+	}
+}
@@ -0,0 +1,229 @@
+package ntree
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrNotRoot is returned when decoding into a node that has a parent or siblings, whose links decoding would break.
+var ErrNotRoot = errors.New("ntree: node is not a root")
+
+// gobTree is the wire form of a tree: values and child counts in pre-order, without any back pointers.
+type gobTree struct {
+	Values   []interface{}
+	Children []int
+}
+
+// GobEncode implements gob.GobEncoder.  Only the values and the child structure below n are sent, so
+// concrete value types must be registered with gob.Register.
+func (n *Node) GobEncode() ([]byte, error) {
+	t := gobTree{}
+	for current := n; current != nil; current = nextPreOrder(n, current) {
+		children := 0
+		for child := current.Children; child != nil; child = child.Next {
+			children++
+		}
+		t.Values = append(t.Values, current.Value)
+		t.Children = append(t.Children, children)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&t); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder.  n, which must be a root, becomes the root of the decoded tree with all
+// Parent, Previous and Next links rebuilt.
+func (n *Node) GobDecode(data []byte) error {
+	if !IsRoot(n) {
+		return ErrNotRoot
+	}
+
+	t := gobTree{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&t); err != nil {
+		return err
+	}
+	if len(t.Values) == 0 || len(t.Values) != len(t.Children) {
+		return ErrMalformed
+	}
+
+	// Keep the subscriptions of n, which stays the same node with a new subtree.
+	*n = Node{ext: n.ext}
+	b := treeBuilder{}
+	for i, v := range t.Values {
+		node := n
+		if i > 0 {
+			node = New(v)
+		} else {
+			n.Value = v
+		}
+		if !b.add(node, t.Children[i]) || (i < len(t.Values)-1) == b.done() {
+			return ErrMalformed
+		}
+	}
+
+	touch(n)
+	return nil
+}
+
+type decodeFrame struct {
+	node      *Node
+	last      *Node
+	remaining uint64
+}
+
+// treeBuilder links nodes given in pre-order together with their child counts.
+type treeBuilder struct {
+	stack []decodeFrame
+}
+
+func (b *treeBuilder) add(n *Node, children int) bool {
+	if children < 0 {
+		return false
+	}
+
+	if len(b.stack) > 0 {
+		top := &b.stack[len(b.stack)-1]
+		n.Parent = top.node
+		if top.last == nil {
+			top.node.Children = n
+		} else {
+			top.last.Next = n
+			n.Previous = top.last
+		}
+		top.last = n
+		top.remaining--
+	}
+
+	for len(b.stack) > 0 && b.stack[len(b.stack)-1].remaining == 0 {
+		b.stack = b.stack[:len(b.stack)-1]
+	}
+	if children > 0 {
+		b.stack = append(b.stack, decodeFrame{node: n, remaining: uint64(children)})
+	}
+	return true
+}
+
+func (b *treeBuilder) done() bool {
+	return len(b.stack) == 0
+}
+
+// MarshalText implements encoding.TextMarshaler.  Each node is written as a quoted value, followed by its
+// children in parentheses when it has any, e.g. "root"("a" "b"("c")).  Values must be strings or implement
+// encoding.TextMarshaler.
+func (n *Node) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	for current := n; current != nil; {
+		text, err := valueText(current.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(strconv.Quote(text))
+
+		if current.Children != nil {
+			buf.WriteByte('(')
+			current = current.Children
+			continue
+		}
+
+		for current != n && current.Next == nil {
+			buf.WriteByte(')')
+			current = current.Parent
+		}
+		if current == n {
+			break
+		}
+		buf.WriteByte(' ')
+		current = current.Next
+	}
+	return buf.Bytes(), nil
+}
+
+func valueText(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case string:
+		return value, nil
+	case encoding.TextMarshaler:
+		b, err := value.MarshalText()
+		return string(b), err
+	}
+	return "", fmt.Errorf("ntree: cannot marshal %T as text", v)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for the format written by MarshalText.  n, which must be a
+// root, becomes the root of the decoded tree, and every value is decoded as a string.
+func (n *Node) UnmarshalText(text []byte) error {
+	if !IsRoot(n) {
+		return ErrNotRoot
+	}
+
+	s := string(text)
+	pos := 0
+	fail := func(msg string) error {
+		return fmt.Errorf("ntree: %s at offset %d", msg, pos)
+	}
+
+	var root, parent *Node
+	var last []*Node
+	for {
+		quoted, err := strconv.QuotedPrefix(s[pos:])
+		if err != nil {
+			return fail("expected quoted value")
+		}
+		value, _ := strconv.Unquote(quoted)
+		pos += len(quoted)
+
+		node := New(value)
+		if root == nil {
+			root = node
+		} else {
+			top := last[len(last)-1]
+			node.Parent = parent
+			if top == nil {
+				parent.Children = node
+			} else {
+				top.Next = node
+				node.Previous = top
+			}
+			last[len(last)-1] = node
+		}
+
+		if pos < len(s) && s[pos] == '(' {
+			pos++
+			parent = node
+			last = append(last, nil)
+			continue
+		}
+
+		for pos < len(s) && s[pos] == ')' && parent != nil {
+			pos++
+			parent = parent.Parent
+			last = last[:len(last)-1]
+		}
+		if parent == nil {
+			break
+		}
+		if pos >= len(s) || s[pos] != ' ' {
+			return fail("expected ' ' or ')'")
+		}
+		pos++
+	}
+	if pos != len(s) {
+		return fail("unexpected trailing data")
+	}
+
+	ext := n.ext
+	*n = *root
+	n.ext = ext
+	for child := n.Children; child != nil; child = child.Next {
+		child.Parent = n
+	}
+	touch(n)
+	return nil
+}
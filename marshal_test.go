@@ -0,0 +1,107 @@
+package ntree_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+type gobEnvelope struct {
+	Name string
+	Tree *ntree.Node
+}
+
+func TestGobEncodeDecode(t *testing.T) {
+	gob.Register(&MockData{})
+
+	nodes := GenerateTree()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gobEnvelope{"tree", nodes["a_1"]}); err != nil {
+		t.Fatal("Gob encoding failed:", err)
+	}
+
+	decoded := gobEnvelope{}
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal("Gob decoding failed:", err)
+	}
+
+	root := decoded.Tree
+	if root == nil || !ntree.IsRoot(root) {
+		t.Fatal("Decoded node should be a root")
+	}
+	if ntree.NodeCount(root, ntree.TraverseAll) != ntree.NodeCount(nodes["a_1"], ntree.TraverseAll) {
+		t.Error("Mismatched node count after gob decoding")
+	}
+
+	expected := []string{"a_1", "a_1_1", "a_1_2", "a_1_3"}
+	i := 0
+	ntree.Traverse(root, ntree.TraversePreOrder, ntree.TraverseAll, -1, func(n *ntree.Node, data interface{}) bool {
+		if n.Value.(*MockData).Id != expected[i] {
+			t.Errorf("Expected %s but got %s", expected[i], n.Value.(*MockData).Id)
+		}
+		if n != root && n.Parent != root {
+			t.Error("Parent link was not rebuilt")
+		}
+		i++
+		return false
+	}, nil)
+
+	last := root.Children.Next.Next
+	if last.Previous.Next != last || last.Previous.Previous != root.Children {
+		t.Error("Sibling links were not rebuilt")
+	}
+
+	if (&ntree.Node{}).GobDecode([]byte("garbage")) == nil {
+		t.Error("GobDecode should fail for garbage input")
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	root := GenerateStringTree()
+	text, err := root.MarshalText()
+	if err != nil {
+		t.Fatal("MarshalText failed:", err)
+	}
+
+	expected := `"root"("a"("a1" "a2") "b"("b1"("b1x")) "")`
+	if string(text) != expected {
+		t.Errorf("Expected %s but got %s", expected, text)
+	}
+
+	decoded := &ntree.Node{}
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal("UnmarshalText failed:", err)
+	}
+	if !sameTree(root, decoded) {
+		t.Error("Decoded tree does not match the marshalled one", decoded)
+	}
+
+	if _, err := ntree.New(1).MarshalText(); err == nil {
+		t.Error("MarshalText should fail for values that are not text")
+	}
+
+	for _, bad := range []string{``, `root`, `"a"(`, `"a"()`, `"a"("b"`, `"a"("b")x`, `"a" "b"`, `"a"("b",)`} {
+		if (&ntree.Node{}).UnmarshalText([]byte(bad)) == nil {
+			t.Errorf("UnmarshalText should fail for %q", bad)
+		}
+	}
+}
+
+func TestDecodeIntoAttachedNode(t *testing.T) {
+	root := GenerateStringTree()
+	data, err := root.GobEncode()
+	if err != nil {
+		t.Fatal("GobEncode failed:", err)
+	}
+
+	tree := GenerateStringTree()
+	a := tree.Children
+	if a.GobDecode(data) != ntree.ErrNotRoot || a.UnmarshalText([]byte(`"x"`)) != ntree.ErrNotRoot {
+		t.Error("Decoding into an attached node should fail")
+	}
+	if !sameTree(tree, GenerateStringTree()) {
+		t.Error("A failed decode should leave the tree untouched")
+	}
+}
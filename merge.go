@@ -0,0 +1,70 @@
+package ntree
+
+// MergeResult describes a merged tree.  Added holds the top nodes of the subtrees copied in from src, and Merged
+// pairs every node of the result (A) with the src node (B) that was merged into it.
+type MergeResult struct {
+	Root   *Node
+	Added  []*Node
+	Merged []NodePair
+}
+
+// Merge merges the tree rooted at src into the one rooted at dst, changing dst in place.  The two roots are
+// merged with each other; below them, children are matched by key level by level, matches are merged recursively
+// and the remaining src children are copied and appended.  key defaults to the node's value and must return
+// comparable keys; children with equal keys are matched in order.  conflict returns the value of merged nodes and
+// defaults to keeping dst's value.  src is not changed.
+func Merge(dst, src *Node, key func(*Node) interface{}, conflict func(dst, src interface{}) interface{}) *MergeResult {
+	if dst == nil || src == nil {
+		return nil
+	}
+	if key == nil {
+		key = func(n *Node) interface{} {
+			return n.Value
+		}
+	}
+	if conflict == nil {
+		conflict = func(dst, src interface{}) interface{} {
+			return dst
+		}
+	}
+
+	result := &MergeResult{Root: dst}
+	stack := []NodePair{{dst, src}}
+	for len(stack) > 0 {
+		pair := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		SetValue(pair.A, conflict(pair.A.Value, pair.B.Value))
+		result.Merged = append(result.Merged, pair)
+
+		candidates := make(map[interface{}][]*Node)
+		for child := pair.A.Children; child != nil; child = child.Next {
+			k := key(child)
+			candidates[k] = append(candidates[k], child)
+		}
+
+		var matches []NodePair
+		for child := pair.B.Children; child != nil; child = child.Next {
+			k := key(child)
+			if c := candidates[k]; len(c) > 0 {
+				matches = append(matches, NodePair{c[0], child})
+				candidates[k] = c[1:]
+				continue
+			}
+
+			added := AppendChild(pair.A, Copy(child))
+			result.Added = append(result.Added, added)
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			stack = append(stack, matches[i])
+		}
+	}
+	return result
+}
+
+// MergeCopy is like Merge but leaves dst untouched and merges into a copy of it.
+func MergeCopy(dst, src *Node, key func(*Node) interface{}, conflict func(dst, src interface{}) interface{}) *MergeResult {
+	if dst == nil || src == nil {
+		return nil
+	}
+	return Merge(Copy(dst), src, key, conflict)
+}
@@ -0,0 +1,383 @@
+package ntree
+
+import (
+	"errors"
+)
+
+var ErrDuplicateKey = errors.New("ntree: duplicate key")
+
+// ConflictKind says how the two sides of a three-way merge disagree.
+type ConflictKind int
+
+const (
+	// ConflictValue: both sides changed a node's value differently.
+	ConflictValue ConflictKind = iota
+	// ConflictDeleteEdit: one side deleted a node that the other side changed, moved or added children to.
+	ConflictDeleteEdit
+	// ConflictMove: both sides moved a node to different parents, or the moves form a cycle.
+	ConflictMove
+)
+
+func (k ConflictKind) String() string {
+	switch k {
+	case ConflictValue:
+		return "value"
+	case ConflictDeleteEdit:
+		return "delete/edit"
+	case ConflictMove:
+		return "move"
+	}
+	return "unknown"
+}
+
+// Side selects one version of a conflicting node.
+type Side int
+
+const (
+	SideOurs Side = iota
+	SideTheirs
+)
+
+// Conflict describes a node the sides disagree on.  Base, Ours and Theirs are the node's versions in the input
+// trees, nil where it is missing.
+type Conflict struct {
+	Kind   ConflictKind
+	Key    interface{}
+	Base   *Node
+	Ours   *Node
+	Theirs *Node
+}
+
+// ConflictResolver settles a conflict by picking a side.  When ok is false the conflict is reported instead and
+// ours wins, except that deleted nodes are kept.
+type ConflictResolver func(c Conflict) (side Side, ok bool)
+
+// Merge3Options configures Merge3.  Key identifies nodes across the three trees and defaults to the node's value;
+// keys must be comparable and unique within each tree.  The roots always correspond.  Equal compares values and
+// defaults to ==.  Resolvers holds an optional resolver for each kind of conflict.
+type Merge3Options struct {
+	Key       func(*Node) interface{}
+	Equal     func(a, b interface{}) bool
+	Resolvers map[ConflictKind]ConflictResolver
+}
+
+const (
+	merge3Base = iota
+	merge3Ours
+	merge3Theirs
+)
+
+// merge3Root stands in for the key of the roots, which correspond whatever their keys.
+type merge3Root struct{}
+
+type merge3Entry struct {
+	key     interface{}
+	nodes   [3]*Node
+	parents [3]interface{}
+
+	kept    bool
+	settled bool
+	value   interface{}
+	parent  interface{}
+	side    Side
+}
+
+type merge3 struct {
+	opts      Merge3Options
+	entries   map[interface{}]*merge3Entry
+	order     []interface{}
+	keys      map[*Node]interface{}
+	conflicts []Conflict
+}
+
+// Merge3 merges the changes made to base in ours and theirs and returns the merged tree along with the conflicts
+// that no resolver settled.  Nodes keep their value and parent unless a side changed them.  Sibling order follows
+// ours; nodes that only theirs puts under a parent follow their preceding sibling in theirs.  The input trees are
+// not changed and values are copied by assignment.
+func Merge3(base, ours, theirs *Node, opts Merge3Options) (*Node, []Conflict, error) {
+	if base == nil || ours == nil || theirs == nil {
+		return nil, nil, ErrInvalidNode
+	}
+	if opts.Key == nil {
+		opts.Key = func(n *Node) interface{} {
+			return n.Value
+		}
+	}
+	if opts.Equal == nil {
+		opts.Equal = func(a, b interface{}) bool {
+			return a == b
+		}
+	}
+
+	m := &merge3{opts: opts, entries: make(map[interface{}]*merge3Entry), keys: make(map[*Node]interface{})}
+	for i, root := range []*Node{base, ours, theirs} {
+		if err := m.index(i, root); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, k := range m.order {
+		m.decide(m.entries[k])
+	}
+	m.keepParents()
+	m.breakCycles()
+	return m.build(), m.conflicts, nil
+}
+
+func (m *merge3) index(version int, root *Node) error {
+	for n := root; n != nil; n = nextPreOrder(root, n) {
+		var k interface{} = merge3Root{}
+		if n != root {
+			k = m.opts.Key(n)
+		}
+		m.keys[n] = k
+
+		e, ok := m.entries[k]
+		if !ok {
+			e = &merge3Entry{key: m.opts.Key(n)}
+			m.entries[k] = e
+			m.order = append(m.order, k)
+		}
+		if e.nodes[version] != nil {
+			return ErrDuplicateKey
+		}
+		e.nodes[version] = n
+		if n != root {
+			e.parents[version] = m.keys[n.Parent]
+		}
+	}
+	return nil
+}
+
+// resolve asks the resolver for kind to settle the conflict over e, reporting it if none does.
+func (m *merge3) resolve(kind ConflictKind, e *merge3Entry) (Side, bool) {
+	c := Conflict{Kind: kind, Key: e.key, Base: e.nodes[merge3Base], Ours: e.nodes[merge3Ours], Theirs: e.nodes[merge3Theirs]}
+	if resolver := m.opts.Resolvers[kind]; resolver != nil {
+		if side, ok := resolver(c); ok {
+			return side, true
+		}
+	}
+	m.conflicts = append(m.conflicts, c)
+	return SideOurs, false
+}
+
+// choose3 picks the side that changed a property, reporting whether both changed it differently.
+func choose3(base, ours, theirs interface{}, hasBase bool, equal func(a, b interface{}) bool) (Side, bool) {
+	if !hasBase {
+		return SideOurs, !equal(ours, theirs)
+	}
+
+	oursChanged, theirsChanged := !equal(base, ours), !equal(base, theirs)
+	if oursChanged && theirsChanged && !equal(ours, theirs) {
+		return SideOurs, true
+	}
+	if theirsChanged && !oursChanged {
+		return SideTheirs, false
+	}
+	return SideOurs, false
+}
+
+func keysEqual(a, b interface{}) bool {
+	return a == b
+}
+
+// take keeps e as it is in the version of side.
+func (e *merge3Entry) take(side Side) {
+	version := merge3Ours
+	if side == SideTheirs {
+		version = merge3Theirs
+	}
+	e.kept = true
+	e.value = e.nodes[version].Value
+	e.parent = e.parents[version]
+	e.side = side
+}
+
+func (m *merge3) decide(e *merge3Entry) {
+	b, o, t := e.nodes[merge3Base], e.nodes[merge3Ours], e.nodes[merge3Theirs]
+	switch {
+	case o != nil && t != nil:
+		e.kept = true
+		side, conflict := choose3(valueOf(b), o.Value, t.Value, b != nil, m.opts.Equal)
+		if conflict {
+			side, _ = m.resolve(ConflictValue, e)
+		}
+		e.value = e.nodes[merge3Ours+int(side)].Value
+
+		side, conflict = choose3(e.parents[merge3Base], e.parents[merge3Ours], e.parents[merge3Theirs], b != nil, keysEqual)
+		if conflict {
+			side, _ = m.resolve(ConflictMove, e)
+		}
+		e.parent = e.parents[merge3Ours+int(side)]
+		e.side = side
+
+	case b == nil && o != nil:
+		e.take(SideOurs)
+
+	case b == nil && t != nil:
+		e.take(SideTheirs)
+
+	case o != nil || t != nil:
+		present, deleting := SideOurs, SideTheirs
+		if o == nil {
+			present, deleting = SideTheirs, SideOurs
+		}
+		version := merge3Ours + int(present)
+		if m.opts.Equal(b.Value, e.nodes[version].Value) && e.parents[merge3Base] == e.parents[version] {
+			return
+		}
+		if side, ok := m.resolve(ConflictDeleteEdit, e); ok && side == deleting {
+			e.settled = true
+			return
+		}
+		e.take(present)
+	}
+}
+
+func valueOf(n *Node) interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.Value
+}
+
+// keepParents deals with kept nodes whose parent one side deleted.  Adding to a deleted node counts as editing it,
+// and nodes below a deletion that was settled are dropped.
+func (m *merge3) keepParents() {
+	for changed := true; changed; {
+		changed = false
+		for _, k := range m.order {
+			e := m.entries[k]
+			if !e.kept || k == (merge3Root{}) {
+				continue
+			}
+			parent := m.entries[e.parent]
+			if parent.kept {
+				continue
+			}
+
+			changed = true
+			if !parent.settled && (parent.nodes[merge3Ours] != nil || parent.nodes[merge3Theirs] != nil) {
+				present, deleting := SideOurs, SideTheirs
+				if parent.nodes[merge3Ours] == nil {
+					present, deleting = SideTheirs, SideOurs
+				}
+				if side, ok := m.resolve(ConflictDeleteEdit, parent); !ok || side != deleting {
+					parent.take(present)
+					continue
+				}
+				parent.settled = true
+			}
+			e.kept = false
+			e.settled = true
+		}
+	}
+}
+
+// breakCycles undoes moves taken from theirs that put a node below itself, reporting them as move conflicts.
+func (m *merge3) breakCycles() {
+	for changed := true; changed; {
+		changed = false
+		for _, k := range m.order {
+			if !m.entries[k].kept {
+				continue
+			}
+			if cycle := m.cycleFrom(k); cycle != nil {
+				m.breakCycle(cycle)
+				changed = true
+			}
+		}
+	}
+}
+
+// cycleFrom returns the keys of the cycle reached by following parents from k, if there is one.
+func (m *merge3) cycleFrom(k interface{}) []interface{} {
+	path := make(map[interface{}]bool)
+	for current := k; current != (merge3Root{}); current = m.entries[current].parent {
+		if !path[current] {
+			path[current] = true
+			continue
+		}
+
+		var cycle []interface{}
+		for c := current; len(cycle) == 0 || c != current; c = m.entries[c].parent {
+			cycle = append(cycle, c)
+		}
+		return cycle
+	}
+	return nil
+}
+
+// breakCycle moves a node of the cycle back to its parent in ours, or to the root when no node has one.
+func (m *merge3) breakCycle(cycle []interface{}) {
+	e := m.entries[cycle[0]]
+	for _, c := range cycle {
+		candidate := m.entries[c]
+		if candidate.side == SideTheirs && candidate.nodes[merge3Ours] != nil && m.entries[candidate.parents[merge3Ours]].kept {
+			e = candidate
+			break
+		}
+	}
+
+	m.conflicts = append(m.conflicts, Conflict{Kind: ConflictMove, Key: e.key, Base: e.nodes[merge3Base], Ours: e.nodes[merge3Ours], Theirs: e.nodes[merge3Theirs]})
+	e.parent, e.side = merge3Root{}, SideOurs
+	if e.nodes[merge3Ours] != nil && m.entries[e.parents[merge3Ours]].kept {
+		e.parent = e.parents[merge3Ours]
+	}
+}
+
+func (m *merge3) build() *Node {
+	merged := make(map[interface{}]*Node)
+	for _, k := range m.order {
+		if e := m.entries[k]; e.kept {
+			merged[k] = New(e.value)
+		}
+	}
+
+	placed := make(map[interface{}]bool)
+	below := func(child *Node, parent interface{}) (interface{}, bool) {
+		k := m.keys[child]
+		e := m.entries[k]
+		return k, e.kept && e.parent == parent && !placed[k]
+	}
+	for _, k := range m.order {
+		n, ok := merged[k]
+		if !ok {
+			continue
+		}
+		e := m.entries[k]
+
+		if o := e.nodes[merge3Ours]; o != nil {
+			for child := o.Children; child != nil; child = child.Next {
+				if ck, ok := below(child, k); ok {
+					AppendChild(n, merged[ck])
+					placed[ck] = true
+				}
+			}
+		}
+		if t := e.nodes[merge3Theirs]; t != nil {
+			var previous *Node
+			for child := t.Children; child != nil; child = child.Next {
+				ck := m.keys[child]
+				if _, ok := below(child, k); ok {
+					if previous == nil {
+						InsertBefore(n, n.Children, merged[ck])
+					} else {
+						InsertBefore(n, previous.Next, merged[ck])
+					}
+					placed[ck] = true
+				}
+				if c, ok := merged[ck]; ok && c.Parent == n {
+					previous = c
+				}
+			}
+		}
+	}
+
+	for _, k := range m.order {
+		if e := m.entries[k]; e.kept && !placed[k] {
+			AppendChild(merged[e.parent], merged[k])
+		}
+	}
+	return merged[merge3Root{}]
+}
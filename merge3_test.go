@@ -0,0 +1,100 @@
+package ntree_test
+
+import (
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func formatLabels(n *ntree.Node) string {
+	s := n.Value.(string)
+	if n.Children == nil {
+		return s
+	}
+
+	var children []string
+	for child := n.Children; child != nil; child = child.Next {
+		children = append(children, formatLabels(child))
+	}
+	return s + "(" + strings.Join(children, " ") + ")"
+}
+
+func labelKey(n *ntree.Node) interface{} {
+	return strings.SplitN(n.Value.(string), "=", 2)[0]
+}
+
+func merge3Labels(t *testing.T, base, ours, theirs string, resolvers map[ntree.ConflictKind]ntree.ConflictResolver) (string, []ntree.Conflict) {
+	t.Helper()
+	merged, conflicts, err := ntree.Merge3(parseLabels(base), parseLabels(ours), parseLabels(theirs), ntree.Merge3Options{Key: labelKey, Resolvers: resolvers})
+	if err != nil {
+		t.Fatal("Merge3 failed:", err)
+	}
+	return formatLabels(merged), conflicts
+}
+
+func TestMerge3(t *testing.T) {
+	cases := []struct {
+		base, ours, theirs string
+		expected           string
+		conflicts          []ntree.ConflictKind
+	}{
+		{"r(a=1(x y) b c)", "r(a=2(x y) b c d)", "r(a=1(x) c b=3 e)", "r(a=2(x) b=3 e c d)", nil},
+		{"r(a=1)", "r(a=2)", "r(a=3)", "r(a=2)", []ntree.ConflictKind{ntree.ConflictValue}},
+		{"r(a)", "r(a b=1)", "r(a b=2)", "r(a b=1)", []ntree.ConflictKind{ntree.ConflictValue}},
+		{"r(a(x) b)", "r(a(x=1) b)", "r(b)", "r(a(x=1) b)", []ntree.ConflictKind{ntree.ConflictDeleteEdit, ntree.ConflictDeleteEdit}},
+		{"r(a b)", "r(a(n) b)", "r(b)", "r(a(n) b)", []ntree.ConflictKind{ntree.ConflictDeleteEdit}},
+		{"r(a(x) b)", "r(a(x) b)", "r(b)", "r(b)", nil},
+		{"r(a b c(x))", "r(a(x) b c)", "r(a b(x) c)", "r(a(x) b c)", []ntree.ConflictKind{ntree.ConflictMove}},
+		{"r(a b)", "r(a(b))", "r(b(a))", "r(a(b))", []ntree.ConflictKind{ntree.ConflictMove}},
+		{"r=1", "r=1", "r=2", "r=2", nil},
+	}
+
+	for _, c := range cases {
+		merged, conflicts := merge3Labels(t, c.base, c.ours, c.theirs, nil)
+		if merged != c.expected {
+			t.Errorf("Merging %s and %s into %s gave %s but expected %s", c.ours, c.theirs, c.base, merged, c.expected)
+		}
+		if len(conflicts) != len(c.conflicts) {
+			t.Errorf("Expected conflicts %v but got %v", c.conflicts, conflicts)
+			continue
+		}
+		for i := range conflicts {
+			if conflicts[i].Kind != c.conflicts[i] {
+				t.Errorf("Expected conflicts %v but got %v", c.conflicts, conflicts)
+			}
+		}
+	}
+}
+
+func TestMerge3Conflicts(t *testing.T) {
+	_, conflicts := merge3Labels(t, "r(a=1 c(x))", "r(a=2 c(x=1))", "r(a=3)", nil)
+	if len(conflicts) != 3 {
+		t.Fatal("Expected 3 conflicts but got", conflicts)
+	}
+	value, deleted := conflicts[0], conflicts[1]
+	if value.Key != "a" || value.Base.Value != "a=1" || value.Ours.Value != "a=2" || value.Theirs.Value != "a=3" {
+		t.Error("Unexpected value conflict", value)
+	}
+	if deleted.Key != "x" || deleted.Ours.Value != "x=1" || deleted.Theirs != nil || deleted.Kind.String() != "delete/edit" {
+		t.Error("Unexpected delete/edit conflict", deleted)
+	}
+
+	theirs := func(c ntree.Conflict) (ntree.Side, bool) {
+		return ntree.SideTheirs, true
+	}
+	resolvers := map[ntree.ConflictKind]ntree.ConflictResolver{ntree.ConflictValue: theirs, ntree.ConflictDeleteEdit: theirs, ntree.ConflictMove: theirs}
+	merged, conflicts := merge3Labels(t, "r(a=1 c(x))", "r(a=2 c(x=1))", "r(a=3)", resolvers)
+	if merged != "r(a=3)" || len(conflicts) != 0 {
+		t.Error("Resolvers should settle every conflict, got", merged, conflicts)
+	}
+
+	merged, conflicts = merge3Labels(t, "r(a b c(x))", "r(a(x) b c)", "r(a b(x) c)", resolvers)
+	if merged != "r(a b(x) c)" || len(conflicts) != 0 {
+		t.Error("Move resolver should pick theirs, got", merged, conflicts)
+	}
+
+	if _, _, err := ntree.Merge3(parseLabels("r(a a)"), parseLabels("r"), parseLabels("r"), ntree.Merge3Options{}); err != ntree.ErrDuplicateKey {
+		t.Error("Expected ErrDuplicateKey but got", err)
+	}
+}
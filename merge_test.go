@@ -0,0 +1,74 @@
+package ntree_test
+
+import (
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func GenerateOtherStringTree() *ntree.Node {
+	root := ntree.New("other")
+	a := ntree.AppendChild(root, ntree.New("a"))
+	ntree.AppendChild(a, ntree.New("a3"))
+	ntree.AppendChild(a, ntree.New("a1"))
+	c := ntree.AppendChild(root, ntree.New("c"))
+	ntree.AppendChild(c, ntree.New("c1"))
+	return root
+}
+
+func TestMerge(t *testing.T) {
+	dst := GenerateStringTree()
+	src := GenerateOtherStringTree()
+	srcCopy := ntree.Copy(src)
+
+	result := ntree.Merge(dst, src, nil, nil)
+	if result.Root != dst || dst.Value != "root" {
+		t.Error("Merge should merge into dst and keep its values by default")
+	}
+	checkChildren(t, dst, "a", "b", "", "c")
+	checkChildren(t, dst.Children, "a1", "a2", "a3")
+	checkChildren(t, dst.Children.Next.Next.Next, "c1")
+	if !ntree.Equal(src, srcCopy, nil) {
+		t.Error("Merge should not change src")
+	}
+
+	if len(result.Added) != 2 || result.Added[0].Value != "c" || result.Added[1].Value != "a3" {
+		t.Error("Unexpected added nodes", result.Added)
+	}
+	for _, n := range result.Added {
+		if root, _ := ntree.GetRoot(n); root != dst {
+			t.Error("Added nodes should be part of the result")
+		}
+	}
+	if len(result.Merged) != 3 || result.Merged[1].A != dst.Children || result.Merged[2].B != src.Children.Children.Next {
+		t.Error("Unexpected merged pairs", result.Merged)
+	}
+}
+
+func TestMergeCopy(t *testing.T) {
+	dst := GenerateStringTree()
+	dstCopy := ntree.Copy(dst)
+	src := GenerateOtherStringTree()
+
+	key := func(n *ntree.Node) interface{} {
+		if s := n.Value.(string); s != "" {
+			return s[:1]
+		}
+		return ""
+	}
+	conflict := func(dst, src interface{}) interface{} {
+		return dst.(string) + "+" + src.(string)
+	}
+	result := ntree.MergeCopy(dst, src, key, conflict)
+	if !ntree.Equal(dst, dstCopy, nil) {
+		t.Error("MergeCopy should not change dst")
+	}
+	if result.Root.Value != "root+other" {
+		t.Error("Roots should be merged with conflict, got", result.Root.Value)
+	}
+	checkChildren(t, result.Root, "a+a", "b", "", "c")
+	checkChildren(t, result.Root.Children, "a1+a3", "a2+a1")
+	if len(result.Added) != 1 || len(result.Merged) != 4 {
+		t.Error("Expected 1 added and 4 merged nodes but got", len(result.Added), len(result.Merged))
+	}
+}
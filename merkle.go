@@ -0,0 +1,209 @@
+package ntree
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// Merkle keeps a SHA-256 digest for every node of a tree, computed from the node's encoded value and the digests
+// of its children in order.
+type Merkle struct {
+	root    *Node
+	encoder ValueEncoder
+	entries map[*Node]merkleEntry
+}
+
+// merkleEntry remembers which children a digest was computed from, so that Update can drop the digests of
+// children that have since been removed.
+type merkleEntry struct {
+	digest   [sha256.Size]byte
+	children []*Node
+}
+
+// MerkleProof shows that a node with the encoded Value and the Children digests is part of a tree with a given
+// root digest.  Steps go from the node's parent up to the root.
+type MerkleProof struct {
+	Value    []byte
+	Children [][sha256.Size]byte
+	Steps    []MerkleStep
+}
+
+// MerkleStep holds an ancestor's encoded value and the digests of the siblings on either side of the path.
+type MerkleStep struct {
+	Value []byte
+	Left  [][sha256.Size]byte
+	Right [][sha256.Size]byte
+}
+
+// MerkleTree computes the digests of the tree rooted at root.  A nil encoder uses DefaultValueEncoder.
+func MerkleTree(root *Node, encoder ValueEncoder) *Merkle {
+	if root == nil {
+		return nil
+	}
+	if encoder == nil {
+		encoder = DefaultValueEncoder
+	}
+
+	m := &Merkle{root: root, encoder: encoder, entries: make(map[*Node]merkleEntry)}
+	m.fill(root)
+	return m
+}
+
+func merkleDigest(value []byte, children [][sha256.Size]byte) [sha256.Size]byte {
+	h := sha256.New()
+	var scratch [binary.MaxVarintLen64]byte
+	h.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(value)))])
+	h.Write(value)
+	h.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(children)))])
+	for _, c := range children {
+		h.Write(c[:])
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func (m *Merkle) childDigests(n *Node) [][sha256.Size]byte {
+	var children [][sha256.Size]byte
+	for child := n.Children; child != nil; child = child.Next {
+		children = append(children, m.entries[child].digest)
+	}
+	return children
+}
+
+func (m *Merkle) compute(n *Node) {
+	m.entries[n] = merkleEntry{
+		digest:   merkleDigest(m.encoder(n.Value), m.childDigests(n)),
+		children: childList(n),
+	}
+}
+
+// fill computes the digest of every node below n that has none, and then of n itself.
+func (m *Merkle) fill(n *Node) {
+	nodes, _ := postOrder(n)
+	for _, current := range nodes[1:] {
+		if _, ok := m.entries[current]; !ok || current == n {
+			m.compute(current)
+		}
+	}
+}
+
+// forget drops the digests of the subtree rooted at n.
+func (m *Merkle) forget(n *Node) {
+	nodes, _ := postOrder(n)
+	for _, current := range nodes[1:] {
+		delete(m.entries, current)
+	}
+}
+
+// Root returns the digest of the root node.
+func (m *Merkle) Root() [sha256.Size]byte {
+	return m.entries[m.root].digest
+}
+
+// Digest returns the digest of n, if it has one.
+func (m *Merkle) Digest(n *Node) ([sha256.Size]byte, bool) {
+	e, ok := m.entries[n]
+	return e.digest, ok
+}
+
+// Update recomputes the digest of n and its ancestors after n's value or children changed.  New children of n get
+// digests for their whole subtree, and children that have left the tree are forgotten.  A subtree moved elsewhere
+// in the tree keeps its digests, so changes made to it while it was detached need their own Update.
+func (m *Merkle) Update(n *Node) error {
+	if root, _ := GetRoot(n); root != m.root {
+		return ErrNotIndexed
+	}
+
+	for _, child := range m.entries[n].children {
+		if root, _ := GetRoot(child); root != m.root {
+			m.forget(child)
+		}
+	}
+	for child := n.Children; child != nil; child = child.Next {
+		if _, ok := m.entries[child]; !ok {
+			m.fill(child)
+		}
+	}
+
+	for ; n != nil; n = n.Parent {
+		m.compute(n)
+	}
+	return nil
+}
+
+// Proof returns an inclusion proof for n.
+func (m *Merkle) Proof(n *Node) (*MerkleProof, error) {
+	if _, ok := m.entries[n]; !ok {
+		return nil, ErrNotIndexed
+	}
+	if root, _ := GetRoot(n); root != m.root {
+		return nil, ErrNotIndexed
+	}
+
+	proof := &MerkleProof{Value: m.encoder(n.Value), Children: m.childDigests(n)}
+	for ; n.Parent != nil; n = n.Parent {
+		step := MerkleStep{Value: m.encoder(n.Parent.Value)}
+		for sibling := n.Parent.Children; sibling != n; sibling = sibling.Next {
+			step.Left = append(step.Left, m.entries[sibling].digest)
+		}
+		for sibling := n.Next; sibling != nil; sibling = sibling.Next {
+			step.Right = append(step.Right, m.entries[sibling].digest)
+		}
+		proof.Steps = append(proof.Steps, step)
+	}
+	return proof, nil
+}
+
+// VerifyProof reports whether proof leads to rootHash.
+func VerifyProof(rootHash [sha256.Size]byte, proof *MerkleProof) bool {
+	if proof == nil {
+		return false
+	}
+
+	h := merkleDigest(proof.Value, proof.Children)
+	for _, step := range proof.Steps {
+		children := append(append(append([][sha256.Size]byte{}, step.Left...), h), step.Right...)
+		h = merkleDigest(step.Value, children)
+	}
+	return h == rootHash
+}
+
+// DiffByHash returns the pairs of corresponding subtrees that differ between a and b, descending only into nodes
+// whose digests differ.  A pair is reported when the nodes' values or numbers of children differ; otherwise the
+// differing children are compared in order.
+func DiffByHash(a, b *Merkle) ([]NodePair, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("ntree: nil merkle tree")
+	}
+
+	var pairs []NodePair
+	stack := []NodePair{{a.root, b.root}}
+	for len(stack) > 0 {
+		pair := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if a.entries[pair.A].digest == b.entries[pair.B].digest {
+			continue
+		}
+
+		x, y := pair.A.Children, pair.B.Children
+		for x != nil && y != nil {
+			x, y = x.Next, y.Next
+		}
+		if x != nil || y != nil || string(a.encoder(pair.A.Value)) != string(b.encoder(pair.B.Value)) {
+			pairs = append(pairs, pair)
+			continue
+		}
+
+		var children []NodePair
+		for x, y := pair.A.Children, pair.B.Children; x != nil; x, y = x.Next, y.Next {
+			children = append(children, NodePair{x, y})
+		}
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return pairs, nil
+}
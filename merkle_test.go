@@ -0,0 +1,109 @@
+package ntree_test
+
+import (
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func TestMerkleTree(t *testing.T) {
+	a := GenerateStringTree()
+	m := ntree.MerkleTree(a, nil)
+	if m.Root() != ntree.MerkleTree(ntree.Copy(a), nil).Root() {
+		t.Error("Equal trees should have equal root digests")
+	}
+
+	before := m.Root()
+	a1 := a.Children.Children
+	digestB, _ := m.Digest(a.Children.Next)
+
+	a1.Value = "changed"
+	if err := m.Update(a1); err != nil {
+		t.Fatal("Update failed:", err)
+	}
+	if m.Root() == before || m.Root() != ntree.MerkleTree(a, nil).Root() {
+		t.Error("Root digest should follow a value change")
+	}
+	if d, _ := m.Digest(a.Children.Next); d != digestB {
+		t.Error("Digests outside the changed path should be kept")
+	}
+
+	added := ntree.AppendChild(a.Children.Next, ntree.New("new"))
+	ntree.AppendChild(added, ntree.New("deeper"))
+	m.Update(a.Children.Next)
+	if m.Root() != ntree.MerkleTree(a, nil).Root() {
+		t.Error("Root digest should follow an insertion")
+	}
+
+	removed := a.Children
+	ntree.Unlink(removed)
+	m.Update(a)
+	if m.Root() != ntree.MerkleTree(a, nil).Root() {
+		t.Error("Root digest should follow a removal")
+	}
+	if _, ok := m.Digest(removed.Children); ok {
+		t.Error("Digests of removed nodes should be forgotten")
+	}
+	if m.Update(removed) != ntree.ErrNotIndexed {
+		t.Error("Update should reject nodes outside the tree")
+	}
+}
+
+func TestMerkleProof(t *testing.T) {
+	a := GenerateStringTree()
+	m := ntree.MerkleTree(a, nil)
+
+	for _, n := range []*ntree.Node{a, a.Children, a.Children.Next.Children.Children, a.Children.Next.Next} {
+		proof, err := m.Proof(n)
+		if err != nil {
+			t.Fatal("Proof failed:", err)
+		}
+		if !ntree.VerifyProof(m.Root(), proof) {
+			t.Errorf("Proof for %v should verify", n.Value)
+		}
+
+		proof.Value = []byte("forged")
+		if ntree.VerifyProof(m.Root(), proof) {
+			t.Errorf("Forged proof for %v should not verify", n.Value)
+		}
+	}
+
+	proof, _ := m.Proof(a.Children.Children)
+	if len(proof.Steps) != 2 || len(proof.Steps[0].Right) != 1 || len(proof.Steps[1].Right) != 2 {
+		t.Error("Unexpected proof shape", proof)
+	}
+	proof.Steps[0].Left, proof.Steps[0].Right = proof.Steps[0].Right, proof.Steps[0].Left
+	if ntree.VerifyProof(m.Root(), proof) {
+		t.Error("Proof with swapped siblings should not verify")
+	}
+
+	if _, err := m.Proof(ntree.New("x")); err == nil {
+		t.Error("Proof should fail for a node outside the tree")
+	}
+	if ntree.VerifyProof(m.Root(), nil) {
+		t.Error("nil proof should not verify")
+	}
+}
+
+func TestDiffByHash(t *testing.T) {
+	a := GenerateStringTree()
+	b := ntree.Copy(a)
+	b.Children.Children.Next.Value = "changed"
+	ntree.AppendChild(b.Children.Next.Children, ntree.New("extra"))
+
+	pairs, err := ntree.DiffByHash(ntree.MerkleTree(a, nil), ntree.MerkleTree(b, nil))
+	if err != nil {
+		t.Fatal("DiffByHash failed:", err)
+	}
+	if len(pairs) != 2 || pairs[0].A != a.Children.Children.Next || pairs[1].A != a.Children.Next.Children || pairs[1].B != b.Children.Next.Children {
+		t.Error("Unexpected differing subtrees", pairs)
+	}
+
+	pairs, _ = ntree.DiffByHash(ntree.MerkleTree(a, nil), ntree.MerkleTree(ntree.Copy(a), nil))
+	if len(pairs) != 0 {
+		t.Error("Equal trees should not differ", pairs)
+	}
+	if _, err := ntree.DiffByHash(nil, ntree.MerkleTree(a, nil)); err == nil {
+		t.Error("DiffByHash should reject nil")
+	}
+}
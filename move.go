@@ -0,0 +1,141 @@
+package ntree
+
+import (
+	"errors"
+)
+
+var (
+	ErrCycle           = errors.New("ntree: node would become its own ancestor")
+	ErrInvalidPosition = errors.New("ntree: invalid position")
+	ErrNotSiblings     = errors.New("ntree: nodes are not siblings")
+	ErrInvalidNode     = errors.New("ntree: invalid node")
+)
+
+// isAncestorOrSelf reports whether a is b or one of b's ancestors.
+func isAncestorOrSelf(a, b *Node) bool {
+	for ; b != nil; b = b.Parent {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Move moves n, with its subtree, to be child number position of newParent, counted without n.  A position of -1
+// appends n.  The tree is left untouched when an error is returned, and a Moved event is raised otherwise.
+func Move(n, newParent *Node, position int) error {
+	if n == nil || newParent == nil {
+		return ErrInvalidNode
+	}
+	if isAncestorOrSelf(n, newParent) {
+		return ErrCycle
+	}
+
+	count := 0
+	for child := newParent.Children; child != nil; child = child.Next {
+		if child != n {
+			count++
+		}
+	}
+	if position < -1 || position > count {
+		return ErrInvalidPosition
+	}
+
+	oldParent := n.Parent
+	unlink(n)
+	var sibling *Node
+	if position != -1 {
+		sibling = newParent.Children
+		for ; position > 0; position-- {
+			sibling = sibling.Next
+		}
+	}
+	insertBefore(newParent, sibling, n)
+	notifyMoved(n, oldParent, newParent)
+	return nil
+}
+
+// relocate puts n before next under parent, or makes it a root when parent is nil, without raising events.
+func relocate(n, parent, next *Node) {
+	unlink(n)
+	if parent != nil {
+		insertBefore(parent, next, n)
+	}
+}
+
+// notifyMoved raises a Moved event for n once the whole operation has been made.
+func notifyMoved(n, oldParent, newParent *Node) {
+	notify(Event{Type: Moved, Node: n, OldParent: oldParent, NewParent: newParent}, n, oldParent, newParent)
+}
+
+// SwapSiblings exchanges the positions of two children of the same parent, raising a Moved event for each.
+func SwapSiblings(a, b *Node) error {
+	if a == nil || b == nil {
+		return ErrInvalidNode
+	}
+	if a.Parent == nil || a.Parent != b.Parent {
+		return ErrNotSiblings
+	}
+	if a == b {
+		return nil
+	}
+
+	parent, aNext, bNext := a.Parent, a.Next, b.Next
+	switch {
+	case aNext == b:
+		relocate(a, parent, bNext)
+	case bNext == a:
+		relocate(b, parent, aNext)
+	default:
+		relocate(a, parent, bNext)
+		relocate(b, parent, aNext)
+	}
+	notifyMoved(a, parent, parent)
+	notifyMoved(b, parent, parent)
+	return nil
+}
+
+// SwapSubtrees exchanges the positions of the subtrees rooted at a and b, which may have different parents or
+// belong to different trees.  A root swapped with another node becomes a child in that node's place, and the
+// node becomes a root.  A Moved event is raised for each of a and b.
+func SwapSubtrees(a, b *Node) error {
+	if a == nil || b == nil {
+		return ErrInvalidNode
+	}
+	if a == b {
+		return nil
+	}
+	if isAncestorOrSelf(a, b) || isAncestorOrSelf(b, a) {
+		return ErrCycle
+	}
+	if a.Parent != nil && a.Parent == b.Parent {
+		return SwapSiblings(a, b)
+	}
+
+	aParent, aNext := a.Parent, a.Next
+	bParent, bNext := b.Parent, b.Next
+	unlink(a)
+	relocate(b, aParent, aNext)
+	relocate(a, bParent, bNext)
+	notifyMoved(a, aParent, bParent)
+	notifyMoved(b, bParent, aParent)
+	return nil
+}
+
+// ReplaceNode puts the root node replacement, with its subtree, in old's place.  old keeps its own subtree and
+// becomes a root.  Moved events are raised for old, which leaves its parent, and for replacement.
+func ReplaceNode(old, replacement *Node) error {
+	if old == nil || replacement == nil || old.Parent == nil || !IsRoot(replacement) {
+		return ErrInvalidNode
+	}
+	if isAncestorOrSelf(replacement, old) {
+		return ErrCycle
+	}
+
+	parent, next := old.Parent, old.Next
+	unlink(old)
+	insertBefore(parent, next, replacement)
+	notifyMoved(old, parent, nil)
+	notifyMoved(replacement, nil, parent)
+	return nil
+}
@@ -0,0 +1,168 @@
+package ntree_test
+
+import (
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func childValues(n *ntree.Node) []string {
+	var values []string
+	for child := n.Children; child != nil; child = child.Next {
+		values = append(values, child.Value.(string))
+	}
+	return values
+}
+
+func checkChildren(t *testing.T, n *ntree.Node, expected ...string) {
+	t.Helper()
+	values := childValues(n)
+	if len(values) != len(expected) {
+		t.Errorf("Expected children %v but got %v", expected, values)
+		return
+	}
+	for i := range values {
+		if values[i] != expected[i] {
+			t.Errorf("Expected children %v but got %v", expected, values)
+			return
+		}
+	}
+
+	var previous *ntree.Node
+	for child := n.Children; child != nil; child = child.Next {
+		if child.Parent != n || child.Previous != previous {
+			t.Error("Broken links among the children of", n.Value)
+		}
+		previous = child
+	}
+}
+
+func TestMove(t *testing.T) {
+	root := GenerateStringTree()
+	a, b := root.Children, root.Children.Next
+
+	if err := ntree.Move(b.Children, a, 1); err != nil {
+		t.Fatal("Move failed:", err)
+	}
+	checkChildren(t, a, "a1", "b1", "a2")
+	checkChildren(t, b)
+
+	if err := ntree.Move(a.Children, a, -1); err != nil {
+		t.Fatal("Move failed:", err)
+	}
+	checkChildren(t, a, "b1", "a2", "a1")
+
+	if err := ntree.Move(a.Children.Next.Next, a, 0); err != nil {
+		t.Fatal("Move failed:", err)
+	}
+	checkChildren(t, a, "a1", "b1", "a2")
+
+	before := ntree.Copy(root)
+	if ntree.Move(a, a.Children.Next.Children, 0) != ntree.ErrCycle || ntree.Move(a, a, 0) != ntree.ErrCycle {
+		t.Error("Moving a node below itself should fail")
+	}
+	if ntree.Move(a.Children, b, 1) != ntree.ErrInvalidPosition || ntree.Move(a.Children, a, 3) != ntree.ErrInvalidPosition {
+		t.Error("Moving to a position past the end should fail")
+	}
+	if ntree.Move(nil, a, 0) != ntree.ErrInvalidNode {
+		t.Error("Moving nil should fail")
+	}
+	if !ntree.Equal(root, before, nil) {
+		t.Error("Failed moves should leave the tree untouched")
+	}
+}
+
+func checkMoves(t *testing.T, events []ntree.Event, expected ...ntree.Event) {
+	t.Helper()
+	if len(events) != len(expected) {
+		t.Errorf("Expected events %v but got %v", expected, events)
+		return
+	}
+	for i := range events {
+		if events[i] != expected[i] {
+			t.Errorf("Expected events %v but got %v", expected, events)
+			return
+		}
+	}
+}
+
+func TestSwapSiblings(t *testing.T) {
+	root := GenerateStringTree()
+	a, b, c := root.Children, root.Children.Next, root.Children.Next.Next
+	s, events := recordEvents(root, ntree.ObserveSubtree)
+
+	ntree.SwapSiblings(a, b)
+	checkChildren(t, root, "b", "a", "")
+	checkMoves(t, *events,
+		ntree.Event{Type: ntree.Moved, Node: a, OldParent: root, NewParent: root},
+		ntree.Event{Type: ntree.Moved, Node: b, OldParent: root, NewParent: root})
+	s.Unsubscribe()
+	ntree.SwapSiblings(a, b)
+	checkChildren(t, root, "a", "b", "")
+	ntree.SwapSiblings(c, a)
+	checkChildren(t, root, "", "b", "a")
+	ntree.SwapSiblings(c, c)
+	checkChildren(t, root, "", "b", "a")
+
+	if ntree.SwapSiblings(a, a.Children) != ntree.ErrNotSiblings || ntree.SwapSiblings(root, ntree.New("x")) != ntree.ErrNotSiblings {
+		t.Error("Swapping nodes with different parents should fail")
+	}
+}
+
+func TestSwapSubtrees(t *testing.T) {
+	root := GenerateStringTree()
+	a, b := root.Children, root.Children.Next
+	a1, b1 := a.Children, b.Children
+	s, events := recordEvents(root, ntree.ObserveSubtree)
+
+	if err := ntree.SwapSubtrees(a.Children, b.Children); err != nil {
+		t.Fatal("SwapSubtrees failed:", err)
+	}
+	checkMoves(t, *events,
+		ntree.Event{Type: ntree.Moved, Node: a1, OldParent: a, NewParent: b},
+		ntree.Event{Type: ntree.Moved, Node: b1, OldParent: b, NewParent: a})
+	s.Unsubscribe()
+	checkChildren(t, a, "b1", "a2")
+	checkChildren(t, b, "a1")
+	checkChildren(t, a.Children, "b1x")
+
+	other := ntree.New("other")
+	if err := ntree.SwapSubtrees(other, b); err != nil {
+		t.Fatal("SwapSubtrees failed:", err)
+	}
+	checkChildren(t, root, "a", "other", "")
+	if !ntree.IsRoot(b) {
+		t.Error("Swapped out node should become a root")
+	}
+
+	if ntree.SwapSubtrees(root, a.Children) != ntree.ErrCycle || ntree.SwapSubtrees(a.Children.Children, a) != ntree.ErrCycle {
+		t.Error("Swapping a node with its ancestor should fail")
+	}
+}
+
+func TestReplaceNode(t *testing.T) {
+	root := GenerateStringTree()
+	a := root.Children
+
+	replacement := ntree.New("r")
+	ntree.AppendChild(replacement, ntree.New("r1"))
+	_, events := recordEvents(root, ntree.ObserveSubtree)
+	if err := ntree.ReplaceNode(a, replacement); err != nil {
+		t.Fatal("ReplaceNode failed:", err)
+	}
+	checkMoves(t, *events,
+		ntree.Event{Type: ntree.Moved, Node: a, OldParent: root},
+		ntree.Event{Type: ntree.Moved, Node: replacement, NewParent: root})
+	checkChildren(t, root, "r", "b", "")
+	checkChildren(t, a, "a1", "a2")
+	if !ntree.IsRoot(a) {
+		t.Error("Replaced node should become a root")
+	}
+
+	if ntree.ReplaceNode(root.Children, root.Children.Next) != ntree.ErrInvalidNode || ntree.ReplaceNode(root, ntree.New("x")) != ntree.ErrInvalidNode {
+		t.Error("ReplaceNode should need a root replacement and a node with a parent")
+	}
+	if ntree.ReplaceNode(a.Children, a) != ntree.ErrCycle {
+		t.Error("Replacing a node with its own root should fail")
+	}
+}
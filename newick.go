@@ -0,0 +1,298 @@
+package ntree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NewickValue is the Value ParseNewick gives each node.  HasLength reports whether a branch length was
+// present, and Comments holds the bracketed comments that followed the node's label or length.
+type NewickValue struct {
+	Label     string
+	Length    float64
+	HasLength bool
+	Comments  []string
+}
+
+const newickSpecial = "()[]':;,"
+
+// NewickError reports a syntax error in Newick input.  Offset counts bytes from the start of the input.
+type NewickError struct {
+	Offset int64
+	Msg    string
+}
+
+func (e *NewickError) Error() string {
+	return fmt.Sprintf("ntree: newick: %s at offset %d", e.Msg, e.Offset)
+}
+
+type newickReader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+func (p *newickReader) fail(format string, args ...interface{}) error {
+	return &NewickError{Offset: p.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *newickReader) peek() (byte, bool) {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return 0, false
+	}
+	p.r.UnreadByte()
+	return c, true
+}
+
+func (p *newickReader) next() (byte, bool) {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return 0, false
+	}
+	p.offset++
+	return c, true
+}
+
+// skip consumes whitespace and comments, passing each comment to keep when it is not nil.
+func (p *newickReader) skip(keep *[]string) error {
+	for {
+		c, ok := p.peek()
+		if !ok {
+			return nil
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.next()
+		case c == '[':
+			p.next()
+			var sb strings.Builder
+			for {
+				c, ok := p.next()
+				if !ok {
+					return p.fail("unterminated comment")
+				}
+				if c == ']' {
+					break
+				}
+				sb.WriteByte(c)
+			}
+			if keep != nil {
+				*keep = append(*keep, sb.String())
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *newickReader) label() (string, error) {
+	var sb strings.Builder
+	c, ok := p.peek()
+	if ok && c == '\'' {
+		p.next()
+		for {
+			c, ok := p.next()
+			if !ok {
+				return "", p.fail("unterminated quoted label")
+			}
+			if c == '\'' {
+				if next, ok := p.peek(); ok && next == '\'' {
+					p.next()
+				} else {
+					return sb.String(), nil
+				}
+			}
+			sb.WriteByte(c)
+		}
+	}
+
+	for {
+		c, ok := p.peek()
+		if !ok || strings.IndexByte(newickSpecial, c) >= 0 || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			return sb.String(), nil
+		}
+		p.next()
+		if c == '_' {
+			c = ' '
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// labelLength reads the optional label, branch length and comments that follow a node.
+func (p *newickReader) labelLength(v *NewickValue) error {
+	if err := p.skip(&v.Comments); err != nil {
+		return err
+	}
+	label, err := p.label()
+	if err != nil {
+		return err
+	}
+	v.Label = label
+	if err := p.skip(&v.Comments); err != nil {
+		return err
+	}
+
+	if c, ok := p.peek(); !ok || c != ':' {
+		return nil
+	}
+	p.next()
+	if err := p.skip(&v.Comments); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for {
+		c, ok := p.peek()
+		if !ok || !(c >= '0' && c <= '9' || strings.IndexByte("+-.eE", c) >= 0) {
+			break
+		}
+		p.next()
+		sb.WriteByte(c)
+	}
+	length, err := strconv.ParseFloat(sb.String(), 64)
+	if err != nil {
+		return p.fail("invalid branch length %q", sb.String())
+	}
+	v.Length, v.HasLength = length, true
+	return p.skip(&v.Comments)
+}
+
+// ParseNewick reads every tree in r, each terminated by ';'.  Node values are *NewickValue.
+func ParseNewick(r io.Reader) ([]*Node, error) {
+	p := &newickReader{r: bufio.NewReader(r)}
+
+	var trees []*Node
+	for {
+		if err := p.skip(nil); err != nil {
+			return nil, err
+		}
+		if _, ok := p.peek(); !ok {
+			return trees, nil
+		}
+
+		root, err := p.tree()
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, root)
+	}
+}
+
+func (p *newickReader) tree() (*Node, error) {
+	var root *Node
+	var open []*Node
+	for {
+		// Open every subtree that starts here, then read the leaf at the bottom.
+		if err := p.skip(nil); err != nil {
+			return nil, err
+		}
+		n := New(&NewickValue{})
+		if root == nil {
+			root = n
+		} else {
+			AppendChild(open[len(open)-1], n)
+		}
+		if c, _ := p.peek(); c == '(' {
+			p.next()
+			open = append(open, n)
+			continue
+		}
+		if err := p.labelLength(n.Value.(*NewickValue)); err != nil {
+			return nil, err
+		}
+
+		// Close subtrees until the next sibling or the end of the tree.
+		for {
+			c, ok := p.next()
+			if !ok {
+				return nil, p.fail("unexpected end of input")
+			}
+			switch {
+			case c == ',' && len(open) > 0:
+			case c == ')' && len(open) > 0:
+				closed := open[len(open)-1]
+				open = open[:len(open)-1]
+				if err := p.labelLength(closed.Value.(*NewickValue)); err != nil {
+					return nil, err
+				}
+				continue
+			case c == ';' && len(open) == 0:
+				return root, nil
+			default:
+				return nil, p.fail("unexpected %q", c)
+			}
+			break
+		}
+	}
+}
+
+// WriteNewick writes the tree rooted at root to w in Newick format, terminated by ";\n".  Node values must be
+// NewickValue or *NewickValue.
+func WriteNewick(w io.Writer, root *Node) error {
+	if root == nil {
+		return errors.New("ntree: nil root")
+	}
+
+	bw := bufio.NewWriter(w)
+	n := root
+	for {
+		if n.Children != nil {
+			bw.WriteByte('(')
+			n = n.Children
+			continue
+		}
+
+		for {
+			if err := writeNewickValue(bw, n.Value); err != nil {
+				return err
+			}
+			if n == root {
+				bw.WriteString(";\n")
+				return bw.Flush()
+			}
+			if n.Next != nil {
+				break
+			}
+			bw.WriteByte(')')
+			n = n.Parent
+		}
+		bw.WriteByte(',')
+		n = n.Next
+	}
+}
+
+func writeNewickValue(w *bufio.Writer, value interface{}) error {
+	var v *NewickValue
+	switch t := value.(type) {
+	case NewickValue:
+		v = &t
+	case *NewickValue:
+		v = t
+	default:
+		return fmt.Errorf("ntree: cannot write %T as newick", value)
+	}
+
+	w.WriteString(newickLabel(v.Label))
+	if v.HasLength {
+		w.WriteByte(':')
+		w.WriteString(strconv.FormatFloat(v.Length, 'g', -1, 64))
+	}
+	for _, c := range v.Comments {
+		if strings.IndexByte(c, ']') >= 0 {
+			return fmt.Errorf("ntree: newick comment %q contains ']'", c)
+		}
+		w.WriteString("[" + c + "]")
+	}
+	return nil
+}
+
+func newickLabel(label string) string {
+	if !strings.ContainsAny(label, newickSpecial+"_\t\n\r") {
+		return strings.Replace(label, " ", "_", -1)
+	}
+	return "'" + strings.Replace(label, "'", "''", -1) + "'"
+}
@@ -0,0 +1,110 @@
+package ntree_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func writeNewick(t *testing.T, root *ntree.Node) string {
+	var buf bytes.Buffer
+	if err := ntree.WriteNewick(&buf, root); err != nil {
+		t.Fatal("WriteNewick failed:", err)
+	}
+	return buf.String()
+}
+
+func TestNewickRoundTrip(t *testing.T) {
+	examples := []string{
+		"(,,(,));",
+		"(A,B,(C,D));",
+		"(A,B,(C,D)E)F;",
+		"(:0.1,:0.2,(:0.3,:0.4):0.5);",
+		"(:0.1,:0.2,(:0.3,:0.4):0.5):0;",
+		"(A:0.1,B:0.2,(C:0.3,D:0.4):0.5);",
+		"(A:0.1,B:0.2,(C:0.3,D:0.4)E:0.5)F;",
+		"((B:0.2,(C:0.3,D:0.4)E:0.5)F:0.1)A;",
+		"((A:0.1,B:0.2)C:0.3,D:0.4)E;",
+		"(Homo_sapiens:1e-05,Pan_troglodytes,'it''s':2[&&NHX:S=x])root;",
+		"A;",
+	}
+
+	for _, example := range examples {
+		trees, err := ntree.ParseNewick(strings.NewReader(example))
+		if err != nil || len(trees) != 1 {
+			t.Errorf("ParseNewick failed for %s: %v", example, err)
+			continue
+		}
+		if out := writeNewick(t, trees[0]); out != example+"\n" {
+			t.Errorf("Expected %s but got %s", example, out)
+		}
+	}
+}
+
+func TestParseNewick(t *testing.T) {
+	input := `[file comment] ((A:0.1, B : 0.2)C:0.3,D:0.4)E;
+	('a b'[note]:1.5,c_d)'x;y';`
+
+	trees, err := ntree.ParseNewick(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("ParseNewick failed:", err)
+	}
+	if len(trees) != 2 {
+		t.Fatal("Expected 2 trees but got", len(trees))
+	}
+
+	first := trees[0]
+	if first.Value.(*ntree.NewickValue).Label != "E" || ntree.NodeCount(first, ntree.TraverseAll) != 5 {
+		t.Error("Unexpected first tree", first)
+	}
+	b := first.Children.Children.Next.Value.(*ntree.NewickValue)
+	if b.Label != "B" || !b.HasLength || b.Length != 0.2 {
+		t.Error("Unexpected node B", b)
+	}
+	c := first.Children.Value.(*ntree.NewickValue)
+	if c.Label != "C" || c.Length != 0.3 {
+		t.Error("Unexpected node C", c)
+	}
+	if first.Value.(*ntree.NewickValue).HasLength {
+		t.Error("Root should have no branch length")
+	}
+
+	second := trees[1]
+	if second.Value.(*ntree.NewickValue).Label != "x;y" {
+		t.Error("Quoted label was not parsed", second.Value)
+	}
+	ab := second.Children.Value.(*ntree.NewickValue)
+	if ab.Label != "a b" || ab.Length != 1.5 || len(ab.Comments) != 1 || ab.Comments[0] != "note" {
+		t.Error("Unexpected node 'a b'", ab)
+	}
+	if second.Children.Next.Value.(*ntree.NewickValue).Label != "c d" {
+		t.Error("Underscores in unquoted labels should become spaces")
+	}
+
+	if trees, err := ntree.ParseNewick(strings.NewReader("  ")); err != nil || len(trees) != 0 {
+		t.Error("Empty input should give no trees", err)
+	}
+
+	for _, bad := range []string{"(A,B)", "(A,B;", "A,B;", "(A:x,B);", "('A,B);", "(A,B)[C;", "A);", "A(B);"} {
+		_, err := ntree.ParseNewick(strings.NewReader(bad))
+		if _, ok := err.(*ntree.NewickError); !ok {
+			t.Errorf("ParseNewick should return a *NewickError for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestWriteNewick(t *testing.T) {
+	root := ntree.New(ntree.NewickValue{Label: "root"})
+	ntree.AppendChild(root, ntree.New(&ntree.NewickValue{Label: "a", Length: 2, HasLength: true}))
+	ntree.AppendChild(root, ntree.New(&ntree.NewickValue{Label: "(b)"}))
+
+	if out := writeNewick(t, root); out != "(a:2,'(b)')root;\n" {
+		t.Error("Unexpected Newick output", out)
+	}
+
+	if ntree.WriteNewick(&bytes.Buffer{}, nil) == nil || ntree.WriteNewick(&bytes.Buffer{}, ntree.New("a")) == nil {
+		t.Error("WriteNewick should fail for a nil root or a non Newick value")
+	}
+}
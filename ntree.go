@@ -3,6 +3,7 @@ package ntree
 
 import (
 	"fmt"
+	"sync/atomic"
 )
 
 const (
@@ -29,6 +30,24 @@ type Node struct {
 	Previous *Node
 	Parent   *Node
 	Children *Node
+
+	version uint64
+	ext     *nodeExt
+}
+
+// nodeExt holds the state only observed nodes and nodes with cached attributes need, so that other nodes stay
+// small and comparable.
+type nodeExt struct {
+	observers  []*Subscription
+	attributes map[*Attribute]interface{}
+}
+
+// extension returns the nodeExt of n, allocating it on first use.
+func (n *Node) extension() *nodeExt {
+	if n.ext == nil {
+		n.ext = &nodeExt{}
+	}
+	return n.ext
 }
 
 type nodeVal struct {
@@ -45,6 +64,20 @@ func Unlink(n *Node) {
 		return
 	}
 
+	parent := n.Parent
+	unlink(n)
+	if parent != nil {
+		notify(Event{Type: ChildRemoved, Node: parent, Child: n}, parent)
+	}
+}
+
+// unlink is Unlink without events.
+func unlink(n *Node) {
+	if n.Parent != nil {
+		touch(n.Parent)
+		stamp(n)
+	}
+
 	if n.Previous != nil {
 		n.Previous.Next = n.Next
 	} else if n.Parent != nil {
@@ -109,10 +142,21 @@ func nodeCountFunc(n *Node, flags TraverseFlags, count *int) {
 }
 
 func AppendChild(parent, n *Node) *Node {
+	if appendChild(parent, n) == nil {
+		return nil
+	}
+
+	notify(Event{Type: ChildAdded, Node: parent, Child: n}, parent)
+	return n
+}
+
+// appendChild is AppendChild without events.
+func appendChild(parent, n *Node) *Node {
 	if parent == nil || n == nil || !IsRoot(n) {
 		return nil
 	}
 
+	touch(parent)
 	n.Parent = parent
 	if parent.Children != nil {
 		sibling := parent.Children
@@ -128,6 +172,105 @@ func AppendChild(parent, n *Node) *Node {
 	return n
 }
 
+// generation is the last version handed out by stamp.
+var generation uint64
+
+// stamp gives root a version no root has had before, so that an index built on a tree never mistakes a different
+// tree, or its own tree after a change, for the one it saw.
+func stamp(root *Node) {
+	root.version = atomic.AddUint64(&generation, 1)
+}
+
+// treeSnapshot remembers the tree an index was built on, so that the index can tell when it has changed.
+type treeSnapshot struct {
+	root    *Node
+	top     *Node
+	version uint64
+}
+
+func newTreeSnapshot(root *Node) treeSnapshot {
+	top, _ := GetRoot(root)
+	return treeSnapshot{root: root, top: top, version: top.version}
+}
+
+// stale reports whether the tree has changed, or root has moved to another tree, since the snapshot was taken.
+func (s treeSnapshot) stale() bool {
+	top, _ := GetRoot(s.root)
+	return top != s.top || top.version != s.version
+}
+
+// touch records a structural change to the tree containing n by stamping its root, and drops the cached
+// attributes of n and its ancestors on the way up.
+func touch(n *Node) {
+	for {
+		if n.ext != nil {
+			n.ext.attributes = nil
+		}
+		if n.Parent == nil {
+			break
+		}
+		n = n.Parent
+	}
+	stamp(n)
+}
+
+// InsertBefore inserts n as a child of parent immediately before sibling.  A nil sibling appends n.
+func InsertBefore(parent, sibling, n *Node) *Node {
+	if insertBefore(parent, sibling, n) == nil {
+		return nil
+	}
+
+	notify(Event{Type: ChildAdded, Node: parent, Child: n}, parent)
+	return n
+}
+
+// insertBefore is InsertBefore without events.
+func insertBefore(parent, sibling, n *Node) *Node {
+	if parent == nil || n == nil || !IsRoot(n) || (sibling != nil && sibling.Parent != parent) {
+		return nil
+	}
+
+	if sibling == nil {
+		return appendChild(parent, n)
+	}
+
+	touch(parent)
+	n.Parent = parent
+	n.Next = sibling
+	n.Previous = sibling.Previous
+	if sibling.Previous != nil {
+		sibling.Previous.Next = n
+	} else {
+		parent.Children = n
+	}
+	sibling.Previous = n
+
+	return n
+}
+
+// Copy returns a copy of the subtree rooted at n.  Values are copied by assignment.
+func Copy(n *Node) *Node {
+	if n == nil {
+		return nil
+	}
+
+	c := New(n.Value)
+	var last *Node
+	for child := n.Children; child != nil; child = child.Next {
+		childCopy := Copy(child)
+		childCopy.Parent = c
+		if last == nil {
+			c.Children = childCopy
+		} else {
+			last.Next = childCopy
+			childCopy.Previous = last
+		}
+		last = childCopy
+	}
+
+	return c
+}
+
 func GetRoot(n *Node) (*Node, int) {
 	if n == nil {
 		return nil, 0
@@ -364,7 +507,6 @@ func (n *Node) String() string {
 	}
 
 	currentLevel := 0
-	lastNode := n
 	levels := make([]string, 0, 10)
 	levels = append(levels, "")
 	tFunc := func(node *Node, value interface{}) bool {
@@ -378,7 +520,6 @@ func (n *Node) String() string {
 			n = n.Parent
 		}
 		levels[currentLevel] += fmt.Sprintf("(%v)", node.Value) + "\t"
-		lastNode = node
 		return false
 	}
 
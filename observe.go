@@ -0,0 +1,188 @@
+package ntree
+
+import (
+	"sync/atomic"
+)
+
+// EventType says what kind of change an Event reports.
+type EventType int
+
+const (
+	// ChildAdded: Child was added to Node.
+	ChildAdded EventType = iota
+	// ChildRemoved: Child was unlinked from Node.
+	ChildRemoved
+	// Moved: Node moved from OldParent to NewParent, which may be the same node, or nil when Node was or became a
+	// root.
+	Moved
+	// ValueChanged: the value of Node changed from OldValue to NewValue.
+	ValueChanged
+	// Sorted: the children of Node were reordered.
+	Sorted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case ChildAdded:
+		return "child added"
+	case ChildRemoved:
+		return "child removed"
+	case Moved:
+		return "moved"
+	case ValueChanged:
+		return "value changed"
+	case Sorted:
+		return "sorted"
+	}
+	return "unknown"
+}
+
+// Event describes a change to a tree.  Only the fields that apply to its Type are set.
+type Event struct {
+	Type      EventType
+	Node      *Node
+	Child     *Node
+	OldParent *Node
+	NewParent *Node
+	OldValue  interface{}
+	NewValue  interface{}
+}
+
+// ObserveScope says which changes a subscription receives.
+type ObserveScope int
+
+const (
+	// ObserveNode receives events about the node itself: changes to its value or children, or it being moved.
+	ObserveNode ObserveScope = iota
+	// ObserveSubtree also receives the events about every node below it.
+	ObserveSubtree
+)
+
+// Subscription delivers the events of a node to a function or to the channel C.  Compound operations, such as
+// SwapSubtrees, deliver the events of their steps, and Move delivers a single Moved event.  Changes made by
+// assigning to the fields of a Node directly are not seen; use SetValue to change values.
+type Subscription struct {
+	C <-chan Event
+
+	node    *Node
+	scope   ObserveScope
+	fn      func(Event)
+	ch      chan Event
+	dropped int
+}
+
+// Subscribe calls fn synchronously, while the change is being made, with every event of n within scope.  fn may
+// change the tree, and the events it causes are delivered before Subscribe's caller continues.
+func Subscribe(n *Node, scope ObserveScope, fn func(Event)) *Subscription {
+	if n == nil || fn == nil {
+		return nil
+	}
+	return subscribe(&Subscription{node: n, scope: scope, fn: fn})
+}
+
+// SubscribeChan sends the events of n within scope to C, which has room for size events.  Events that do not fit
+// are dropped and counted, so that the receiver can tell it missed changes.
+func SubscribeChan(n *Node, scope ObserveScope, size int) *Subscription {
+	if n == nil || size < 0 {
+		return nil
+	}
+
+	ch := make(chan Event, size)
+	return subscribe(&Subscription{C: ch, node: n, scope: scope, ch: ch})
+}
+
+// subscriptions counts the live subscriptions, so that notify can return at once when there are none.
+var subscriptions int64
+
+func subscribe(s *Subscription) *Subscription {
+	ext := s.node.extension()
+	ext.observers = append(ext.observers, s)
+	atomic.AddInt64(&subscriptions, 1)
+	return s
+}
+
+// Unsubscribe stops the delivery of events, detaches the subscription from its node and closes C.
+func (s *Subscription) Unsubscribe() {
+	if s == nil || s.node == nil {
+		return
+	}
+
+	// Build a new slice, so that notify can keep iterating over the old one.
+	var observers []*Subscription
+	for _, o := range s.node.ext.observers {
+		if o != s {
+			observers = append(observers, o)
+		}
+	}
+	s.node.ext.observers = observers
+	s.node = nil
+	atomic.AddInt64(&subscriptions, -1)
+	if s.ch != nil {
+		close(s.ch)
+	}
+}
+
+// Dropped returns the number of events that did not fit in C.
+func (s *Subscription) Dropped() int {
+	return s.dropped
+}
+
+func (s *Subscription) deliver(e Event) {
+	if s.fn != nil {
+		s.fn(e)
+		return
+	}
+
+	select {
+	case s.ch <- e:
+	default:
+		s.dropped++
+	}
+}
+
+// notify delivers e to the subscriptions of the affected nodes and to the subtree subscriptions of their
+// ancestors, at most once each.
+func notify(e Event, affected ...*Node) {
+	if atomic.LoadInt64(&subscriptions) == 0 {
+		return
+	}
+
+	var delivered []*Subscription
+	for _, n := range affected {
+		for a := n; a != nil; a = a.Parent {
+			if a.ext == nil {
+				continue
+			}
+			for _, s := range a.ext.observers {
+				if s.node == nil || (a != n && s.scope != ObserveSubtree) || containsSubscription(delivered, s) {
+					continue
+				}
+				if len(affected) > 1 {
+					delivered = append(delivered, s)
+				}
+				s.deliver(e)
+			}
+		}
+	}
+}
+
+func containsSubscription(subscriptions []*Subscription, s *Subscription) bool {
+	for _, o := range subscriptions {
+		if o == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SetValue sets the value of n, drops its cached attributes and raises a ValueChanged event.
+func SetValue(n *Node, v interface{}) {
+	if n == nil {
+		return
+	}
+
+	old := n.Value
+	n.Value = v
+	Invalidate(n)
+	notify(Event{Type: ValueChanged, Node: n, OldValue: old, NewValue: v}, n)
+}
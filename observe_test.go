@@ -0,0 +1,137 @@
+package ntree_test
+
+import (
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func recordEvents(n *ntree.Node, scope ntree.ObserveScope) (*ntree.Subscription, *[]ntree.Event) {
+	var events []ntree.Event
+	s := ntree.Subscribe(n, scope, func(e ntree.Event) {
+		events = append(events, e)
+	})
+	return s, &events
+}
+
+func TestSubscribe(t *testing.T) {
+	root := GenerateStringTree()
+	a, b := root.Children, root.Children.Next
+	_, rootEvents := recordEvents(root, ntree.ObserveSubtree)
+	_, aEvents := recordEvents(a, ntree.ObserveNode)
+
+	x := ntree.AppendChild(a, ntree.New("x"))
+	ntree.SetValue(a, "a'")
+	ntree.Unlink(x)
+	ntree.SortChildren(a, func(x, y interface{}) bool {
+		return x.(string) > y.(string)
+	})
+	ntree.SetValue(a.Children, "ignored")
+	ntree.SetValue(b.Children.Children, "b1x'")
+
+	expected := []ntree.EventType{ntree.ChildAdded, ntree.ValueChanged, ntree.ChildRemoved, ntree.Sorted}
+	if len(*aEvents) != len(expected) {
+		t.Fatal("Unexpected events for a", *aEvents)
+	}
+	for i, e := range *aEvents {
+		if e.Type != expected[i] {
+			t.Error("Expected", expected[i], "but got", e.Type)
+		}
+	}
+	added, changed := (*aEvents)[0], (*aEvents)[1]
+	if added.Node != a || added.Child != x || changed.OldValue != "a" || changed.NewValue != "a'" {
+		t.Error("Unexpected event fields", added, changed)
+	}
+
+	if len(*rootEvents) != 6 || (*rootEvents)[5].Node != b.Children.Children {
+		t.Error("Subtree subscription should see every event below root", *rootEvents)
+	}
+}
+
+func TestSubscribeMove(t *testing.T) {
+	root := GenerateStringTree()
+	a, b := root.Children, root.Children.Next
+	_, aEvents := recordEvents(a, ntree.ObserveSubtree)
+	_, bEvents := recordEvents(b, ntree.ObserveSubtree)
+	_, rootEvents := recordEvents(root, ntree.ObserveSubtree)
+	moved := a.Children
+
+	if err := ntree.Move(moved, b.Children, 0); err != nil {
+		t.Fatal("Move failed:", err)
+	}
+	for _, events := range []*[]ntree.Event{aEvents, bEvents, rootEvents} {
+		if len(*events) != 1 {
+			t.Fatal("Move should raise a single event for each subscription", *events)
+		}
+		e := (*events)[0]
+		if e.Type != ntree.Moved || e.Node != moved || e.OldParent != a || e.NewParent != b.Children {
+			t.Error("Unexpected move event", e)
+		}
+	}
+}
+
+func TestSubscribeChan(t *testing.T) {
+	root := GenerateStringTree()
+	s := ntree.SubscribeChan(root, ntree.ObserveSubtree, 2)
+
+	ntree.SetValue(root, "a")
+	ntree.SetValue(root, "b")
+	ntree.SetValue(root, "c")
+	if s.Dropped() != 1 {
+		t.Error("Expected 1 dropped event but got", s.Dropped())
+	}
+	if e := <-s.C; e.NewValue != "a" {
+		t.Error("Unexpected first event", e)
+	}
+
+	s.Unsubscribe()
+	ntree.SetValue(root, "d")
+	var values []interface{}
+	for e := range s.C {
+		values = append(values, e.NewValue)
+	}
+	if len(values) != 1 || values[0] != "b" {
+		t.Error("Unsubscribe should stop delivery and close the channel", values)
+	}
+	s.Unsubscribe()
+}
+
+func TestUnsubscribeDuringDelivery(t *testing.T) {
+	root := GenerateStringTree()
+	var first, second *ntree.Subscription
+	count := 0
+	first = ntree.Subscribe(root, ntree.ObserveNode, func(e ntree.Event) {
+		count++
+		first.Unsubscribe()
+		second.Unsubscribe()
+	})
+	second = ntree.Subscribe(root, ntree.ObserveNode, func(e ntree.Event) {
+		count++
+	})
+
+	ntree.SetValue(root, "x")
+	ntree.SetValue(root, "y")
+	if count != 1 {
+		t.Error("Unsubscribed functions should not be called, got", count, "calls")
+	}
+}
+
+func TestSubscribeSurvivesDecoding(t *testing.T) {
+	root := ntree.New("root")
+	_, events := recordEvents(root, ntree.ObserveNode)
+
+	data, err := GenerateStringTree().GobEncode()
+	if err != nil {
+		t.Fatal("GobEncode failed:", err)
+	}
+	if err := root.GobDecode(data); err != nil {
+		t.Fatal("GobDecode failed:", err)
+	}
+	if err := root.UnmarshalText([]byte(`"root"("a")`)); err != nil {
+		t.Fatal("UnmarshalText failed:", err)
+	}
+	ntree.AppendChild(root, ntree.New("b"))
+	if len(*events) != 1 || (*events)[0].Type != ntree.ChildAdded {
+		t.Error("Decoding into a node should keep its subscriptions", *events)
+	}
+}
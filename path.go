@@ -0,0 +1,170 @@
+package ntree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LabelFunc names a node in a path string.
+type LabelFunc func(*Node) string
+
+func defaultLabel(n *Node) string {
+	return fmt.Sprint(n.Value)
+}
+
+// Path returns the child indices leading from the root of n's tree to n, which are empty for the root.
+func Path(n *Node) []int {
+	if n == nil {
+		return nil
+	}
+	root, _ := GetRoot(n)
+	return RelativePath(root, n)
+}
+
+// RelativePath returns the child indices leading from start to n, or nil if start is not n or one of its
+// ancestors.
+func RelativePath(start, n *Node) []int {
+	if start == nil || n == nil {
+		return nil
+	}
+
+	path := []int{}
+	for ; n != start; n = n.Parent {
+		if n.Parent == nil {
+			return nil
+		}
+		i := 0
+		for sibling := n.Previous; sibling != nil; sibling = sibling.Previous {
+			i++
+		}
+		path = append(path, i)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// NodeAt returns the node reached from start by following the child indices in path, or nil if there is none.
+func NodeAt(start *Node, path []int) *Node {
+	if start == nil {
+		return nil
+	}
+	return childAtPath(start, path)
+}
+
+// PathString returns the path of n from its root, such as /a/b/c, where each node is named by label.  A nil label
+// uses fmt.Sprint of the value.  Separators and backslashes in labels are escaped with a backslash, as are labels
+// that read . or .., and empty labels are written as \e.
+func PathString(n *Node, label LabelFunc) string {
+	if n == nil {
+		return ""
+	}
+	if label == nil {
+		label = defaultLabel
+	}
+	if n.Parent == nil {
+		return "/"
+	}
+
+	var segments []string
+	for ; n.Parent != nil; n = n.Parent {
+		segments = append(segments, escapeLabel(label(n)))
+	}
+
+	var b strings.Builder
+	for i := len(segments) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(segments[i])
+	}
+	return b.String()
+}
+
+func escapeLabel(s string) string {
+	if s == "" {
+		return `\e`
+	}
+	if s == "." || s == ".." {
+		return strings.Repeat(`\.`, len(s))
+	}
+	return strings.NewReplacer(`\`, `\\`, "/", `\/`).Replace(s)
+}
+
+// pathSegment is one part of a path string; literal segments had escapes and are never . or ..
+type pathSegment struct {
+	label   string
+	literal bool
+}
+
+func splitPath(path string) []pathSegment {
+	var segments []pathSegment
+	var current strings.Builder
+	literal := false
+	flush := func() {
+		if current.Len() > 0 || literal {
+			segments = append(segments, pathSegment{current.String(), literal})
+		}
+		current.Reset()
+		literal = false
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && current.Len() == 0 && strings.HasPrefix(path[i+1:], "e") &&
+			(i+2 == len(path) || path[i+2] == '/'):
+			// A segment of just \e is an empty label.
+			i++
+			literal = true
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			current.WriteByte(path[i])
+			literal = true
+		case path[i] == '/':
+			flush()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	flush()
+	return segments
+}
+
+// Resolve looks up a path string in the form written by PathString.  Paths starting with / are resolved from the
+// root of start's tree and others from start itself.  . stays put, .. goes to the parent and other segments go to
+// the first child with that label.  Empty segments are skipped, and \e reaches a child with an empty label.
+// Resolve returns nil if the path leads nowhere.
+func Resolve(start *Node, path string, label LabelFunc) *Node {
+	if start == nil {
+		return nil
+	}
+	if label == nil {
+		label = defaultLabel
+	}
+
+	n := start
+	if strings.HasPrefix(path, "/") {
+		n, _ = GetRoot(start)
+	}
+
+	for _, segment := range splitPath(path) {
+		switch {
+		case !segment.literal && segment.label == ".":
+		case !segment.literal && segment.label == "..":
+			if n.Parent == nil {
+				return nil
+			}
+			n = n.Parent
+		default:
+			child := n.Children
+			for child != nil && label(child) != segment.label {
+				child = child.Next
+			}
+			if child == nil {
+				return nil
+			}
+			n = child
+		}
+	}
+	return n
+}
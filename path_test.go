@@ -0,0 +1,105 @@
+package ntree_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func TestPath(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	nodes := GenerateRandomTree(r, 200)
+	for _, n := range nodes {
+		path := ntree.Path(n)
+		if ntree.NodeAt(nodes[0], path) != n {
+			t.Fatal("NodeAt should resolve the path of", n.Value)
+		}
+		if depth := ntree.Depth(n); len(path) != depth-1 {
+			t.Fatal("Path length should be the depth below the root")
+		}
+		if n.Parent != nil {
+			relative := ntree.RelativePath(n.Parent, n)
+			if len(relative) != 1 || ntree.NodeAt(n.Parent, relative) != n {
+				t.Fatal("RelativePath should be relative to its start")
+			}
+		}
+	}
+
+	root := GenerateStringTree()
+	if path := ntree.Path(root.Children.Next.Children.Children); fmt.Sprint(path) != "[1 0 0]" {
+		t.Error("Unexpected path", path)
+	}
+	if ntree.RelativePath(root.Children, root.Children.Next.Children) != nil {
+		t.Error("RelativePath should be nil when start is not an ancestor")
+	}
+	if ntree.NodeAt(root, []int{0, 2}) != nil || ntree.NodeAt(root, []int{-1}) != nil {
+		t.Error("NodeAt should return nil for paths that lead nowhere")
+	}
+}
+
+func TestPathString(t *testing.T) {
+	root := GenerateStringTree()
+	b1x := root.Children.Next.Children.Children
+	if s := ntree.PathString(b1x, nil); s != "/b/b1/b1x" {
+		t.Error("Unexpected path string", s)
+	}
+	if s := ntree.PathString(root, nil); s != "/" {
+		t.Error("Unexpected path string for the root", s)
+	}
+
+	odd := ntree.AppendChild(b1x, ntree.New(`x/y\z`))
+	dots := ntree.AppendChild(odd, ntree.New(".."))
+	if s := ntree.PathString(dots, nil); s != `/b/b1/b1x/x\/y\\z/\.\.` {
+		t.Error("Unexpected escaped path string", s)
+	}
+
+	empty := root.Children.Next.Next
+	below := ntree.AppendChild(empty, ntree.New("e"))
+	if s := ntree.PathString(below, nil); s != `/\e/e` {
+		t.Error("Unexpected path string for an empty label", s)
+	}
+
+	for _, n := range []*ntree.Node{root, b1x, odd, dots, root.Children.Children.Next, empty, below} {
+		if ntree.Resolve(b1x, ntree.PathString(n, nil), nil) != n {
+			t.Error("Resolve should find", ntree.PathString(n, nil))
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := GenerateStringTree()
+	a, b := root.Children, root.Children.Next
+
+	cases := []struct {
+		start    *ntree.Node
+		path     string
+		expected *ntree.Node
+	}{
+		{a, "a1", a.Children},
+		{a, "./a2", a.Children.Next},
+		{a, "../b/b1", b.Children},
+		{a.Children, "/b//b1/", b.Children},
+		{b.Children, "../../a/./a2", a.Children.Next},
+		{a, ".", a},
+		{a, "/", root},
+		{a, "", a},
+		{root, "..", nil},
+		{a, "missing", nil},
+		{a, `/\e`, b.Next},
+		{a, `\e`, nil},
+	}
+	for _, c := range cases {
+		if n := ntree.Resolve(c.start, c.path, nil); n != c.expected {
+			t.Errorf("Resolving %q from %v gave %v", c.path, c.start.Value, n)
+		}
+	}
+
+	label := func(n *ntree.Node) string {
+		return fmt.Sprintf("<%v>", n.Value)
+	}
+	if ntree.Resolve(root, "/<b>/<b1>", label) != b.Children || ntree.PathString(b.Children, label) != "/<b>/<b1>" {
+		t.Error("Custom labels should be used in both directions")
+	}
+}
@@ -0,0 +1,297 @@
+package ntree
+
+import (
+	"errors"
+)
+
+var ErrRewriteLimit = errors.New("ntree: rewrite rules did not settle within the pass limit")
+
+// Pattern matches a node and, unless it accepts any subtree, its children in order.  Values are compared with ==.
+type Pattern struct {
+	value       interface{}
+	anyValue    bool
+	anyChildren bool
+	rest        bool
+	name        string
+	children    []*Pattern
+	minDepth    int
+	maxDepth    int
+}
+
+// Lit matches a node whose value is v and whose children match children.
+func Lit(v interface{}, children ...*Pattern) *Pattern {
+	return &Pattern{value: v, children: children, maxDepth: -1}
+}
+
+// Any matches a node with any value whose children match children.
+func Any(children ...*Pattern) *Pattern {
+	return &Pattern{anyValue: true, children: children, maxDepth: -1}
+}
+
+// Var matches any subtree and binds it to name.
+func Var(name string) *Pattern {
+	return &Pattern{anyValue: true, anyChildren: true, name: name, maxDepth: -1}
+}
+
+// Rest matches any number of siblings, including none, and binds them to name unless it is empty.  It may only
+// appear among the children of another pattern.
+func Rest(name string) *Pattern {
+	return &Pattern{rest: true, name: name, maxDepth: -1}
+}
+
+// As returns a copy of p that binds the matched node to name.  A name bound twice in one pattern only matches
+// equal subtrees.
+func (p *Pattern) As(name string) *Pattern {
+	c := *p
+	c.name = name
+	return &c
+}
+
+// AtDepth returns a copy of p that only matches nodes whose Depth is between min and max, where a max of -1 means
+// no limit.
+func (p *Pattern) AtDepth(min, max int) *Pattern {
+	c := *p
+	c.minDepth, c.maxDepth = min, max
+	return &c
+}
+
+// Binding is one way a pattern matched the subtree rooted at Node.  Nodes holds the nodes bound by Var and As,
+// and Lists the siblings bound by Rest.
+type Binding struct {
+	Node  *Node
+	Nodes map[string]*Node
+	Lists map[string][]*Node
+}
+
+type patternMatcher struct {
+	nodes map[string]*Node
+	lists map[string][]*Node
+}
+
+func newPatternMatcher() *patternMatcher {
+	return &patternMatcher{nodes: make(map[string]*Node), lists: make(map[string][]*Node)}
+}
+
+func (m *patternMatcher) binding(n *Node) Binding {
+	b := Binding{Node: n, Nodes: make(map[string]*Node), Lists: make(map[string][]*Node)}
+	for k, v := range m.nodes {
+		b.Nodes[k] = v
+	}
+	for k, v := range m.lists {
+		b.Lists[k] = v
+	}
+	return b
+}
+
+// node matches p against n and calls k for every way it matches, stopping as soon as k returns true.
+func (m *patternMatcher) node(p *Pattern, n *Node, k func() bool) bool {
+	if p.rest || (!p.anyValue && p.value != n.Value) {
+		return false
+	}
+	if p.minDepth > 0 || p.maxDepth >= 0 {
+		if depth := Depth(n); depth < p.minDepth || (p.maxDepth >= 0 && depth > p.maxDepth) {
+			return false
+		}
+	}
+
+	if p.name != "" {
+		if bound, ok := m.nodes[p.name]; ok {
+			if !Equal(bound, n, nil) {
+				return false
+			}
+		} else {
+			m.nodes[p.name] = n
+			defer delete(m.nodes, p.name)
+		}
+	}
+
+	if p.anyChildren {
+		return k()
+	}
+	return m.siblings(p.children, n.Children, k)
+}
+
+// siblings matches patterns against the list of siblings starting at n.
+func (m *patternMatcher) siblings(patterns []*Pattern, n *Node, k func() bool) bool {
+	if len(patterns) == 0 {
+		return n == nil && k()
+	}
+
+	p := patterns[0]
+	if !p.rest {
+		if n == nil {
+			return false
+		}
+		return m.node(p, n, func() bool {
+			return m.siblings(patterns[1:], n.Next, k)
+		})
+	}
+
+	var taken []*Node
+	for next := n; ; next = next.Next {
+		if ok, added := m.bindList(p.name, taken); ok {
+			found := m.siblings(patterns[1:], next, k)
+			if added {
+				delete(m.lists, p.name)
+			}
+			if found {
+				return true
+			}
+		}
+		if next == nil {
+			return false
+		}
+		taken = append(taken, next)
+	}
+}
+
+// bindList binds nodes to name, reporting false if name is already bound to different siblings, and whether it
+// added the binding.
+func (m *patternMatcher) bindList(name string, nodes []*Node) (ok, added bool) {
+	if name == "" {
+		return true, false
+	}
+	if bound, ok := m.lists[name]; ok {
+		if len(bound) != len(nodes) {
+			return false, false
+		}
+		for i := range bound {
+			if !Equal(bound[i], nodes[i], nil) {
+				return false, false
+			}
+		}
+		return true, false
+	}
+	m.lists[name] = append([]*Node{}, nodes...)
+	return true, true
+}
+
+// Match returns every way pattern matches a subtree of root, in pre-order of the subtrees.
+func Match(root *Node, pattern *Pattern) []Binding {
+	if root == nil || pattern == nil {
+		return nil
+	}
+
+	var bindings []Binding
+	m := newPatternMatcher()
+	for n := root; n != nil; n = nextPreOrder(root, n) {
+		m.node(pattern, n, func() bool {
+			bindings = append(bindings, m.binding(n))
+			return false
+		})
+	}
+	return bindings
+}
+
+// matchFirst returns the first way pattern matches n itself.
+func matchFirst(pattern *Pattern, n *Node) (Binding, bool) {
+	m := newPatternMatcher()
+	var b Binding
+	found := m.node(pattern, n, func() bool {
+		b = m.binding(n)
+		return true
+	})
+	return b, found
+}
+
+// Build returns a new node with value v and the given children, unlinking each of them first so that nodes bound
+// by a match can be reused in a replacement.
+func Build(v interface{}, children ...*Node) *Node {
+	n := New(v)
+	for _, child := range children {
+		Unlink(child)
+		AppendChild(n, child)
+	}
+	return n
+}
+
+// Rule rewrites subtrees matching Pattern into the tree returned by Replace, which may be one of the matched nodes
+// or a new tree reusing them.  Replace may return nil or the matched node itself to leave the subtree alone.
+type Rule struct {
+	Name    string
+	Pattern *Pattern
+	Replace func(b Binding) *Node
+}
+
+// RewriteOrder says in which order Rewrite visits nodes in a pass.
+type RewriteOrder int
+
+const (
+	BottomUp RewriteOrder = iota
+	TopDown
+)
+
+// RewriteStrategy configures Rewrite.  MaxPasses limits the number of passes and defaults to 100.
+type RewriteStrategy struct {
+	Order     RewriteOrder
+	MaxPasses int
+}
+
+// Rewrite applies rules to the tree rooted at root in passes until a pass changes nothing.  Each pass visits the
+// nodes present when it starts, in the strategy's order, and rewrites each with the first rule that matches it.
+// Rewrite returns the new root, which differs from root when root itself was rewritten and then takes its place
+// under root's parent, and ErrRewriteLimit along with the current tree when the rules have not settled after
+// MaxPasses passes.
+func Rewrite(root *Node, rules []Rule, strategy RewriteStrategy) (*Node, error) {
+	if root == nil {
+		return nil, nil
+	}
+	if strategy.MaxPasses <= 0 {
+		strategy.MaxPasses = 100
+	}
+
+	for pass := 0; pass < strategy.MaxPasses; pass++ {
+		var nodes []*Node
+		if strategy.Order == TopDown {
+			for n := root; n != nil; n = nextPreOrder(root, n) {
+				nodes = append(nodes, n)
+			}
+		} else {
+			nodes, _ = postOrder(root)
+			nodes = nodes[1:]
+		}
+
+		changed := false
+		for _, n := range nodes {
+			if !isAncestorOrSelf(root, n) {
+				continue
+			}
+			parent, next := n.Parent, n.Next
+			replacement := applyRules(rules, n)
+			if replacement == nil {
+				continue
+			}
+
+			// Replace may have unlinked n to reuse it, so put the replacement back where n was.
+			if n.Parent == parent {
+				Unlink(n)
+			}
+			Unlink(replacement)
+			if n == root {
+				root = replacement
+			}
+			if parent != nil {
+				InsertBefore(parent, next, replacement)
+			}
+			changed = true
+		}
+		if !changed {
+			return root, nil
+		}
+	}
+	return root, ErrRewriteLimit
+}
+
+// applyRules returns the replacement for n from the first rule that matches and changes it, or nil.
+func applyRules(rules []Rule, n *Node) *Node {
+	for _, rule := range rules {
+		b, ok := matchFirst(rule.Pattern, n)
+		if !ok {
+			continue
+		}
+		if replacement := rule.Replace(b); replacement != nil && replacement != n {
+			return replacement
+		}
+	}
+	return nil
+}
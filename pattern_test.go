@@ -0,0 +1,125 @@
+package ntree_test
+
+import (
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		tree    string
+		pattern *ntree.Pattern
+		count   int
+	}{
+		{"*(+(a 0) +(b c) +(0 0))", ntree.Lit("+", ntree.Var("x"), ntree.Lit("0")), 2},
+		{"*(+(a 0) +(b c) +(0 0))", ntree.Any(ntree.Var("x"), ntree.Var("y")), 3},
+		{"f(x y x)", ntree.Lit("f", ntree.Rest("pre"), ntree.Lit("x").As("hit"), ntree.Rest("post")), 2},
+		{"f(x y x)", ntree.Lit("f", ntree.Rest("")), 1},
+		{"f(x y x)", ntree.Lit("f", ntree.Rest("a"), ntree.Lit("y"), ntree.Rest("a")), 1},
+		{"f(x y z)", ntree.Lit("f", ntree.Rest("a"), ntree.Lit("y"), ntree.Rest("a")), 0},
+		{"r(-(a a) -(a b) -(+(a b) +(a b)))", ntree.Lit("-", ntree.Var("x"), ntree.Var("x")), 2},
+		{"r(x(x(x)))", ntree.Var("n").AtDepth(3, 3), 1},
+		{"r(x(x(x)))", ntree.Lit("x", ntree.Rest("")).AtDepth(2, -1), 3},
+		{"r(x(x(x)))", ntree.Lit("x"), 1},
+	}
+
+	for _, c := range cases {
+		if bindings := ntree.Match(parseLabels(c.tree), c.pattern); len(bindings) != c.count {
+			t.Errorf("Expected %d matches in %s but got %d", c.count, c.tree, len(bindings))
+		}
+	}
+
+	bindings := ntree.Match(parseLabels("f(x y x)"), ntree.Lit("f", ntree.Rest("pre"), ntree.Lit("x").As("hit"), ntree.Rest("post")))
+	first, second := bindings[0], bindings[1]
+	if len(first.Lists["pre"]) != 0 || len(first.Lists["post"]) != 2 || first.Nodes["hit"] != first.Node.Children {
+		t.Error("Unexpected first binding", first)
+	}
+	if len(second.Lists["pre"]) != 2 || len(second.Lists["post"]) != 0 || second.Nodes["hit"] != second.Node.Children.Next.Next {
+		t.Error("Unexpected second binding", second)
+	}
+}
+
+var simplifyRules = []ntree.Rule{
+	{"add zero", ntree.Lit("+", ntree.Var("x"), ntree.Lit("0")), func(b ntree.Binding) *ntree.Node {
+		return b.Nodes["x"]
+	}},
+	{"multiply by one", ntree.Lit("*", ntree.Var("x"), ntree.Lit("1")), func(b ntree.Binding) *ntree.Node {
+		return b.Nodes["x"]
+	}},
+	{"multiply by zero", ntree.Lit("*", ntree.Var("x"), ntree.Lit("0")), func(b ntree.Binding) *ntree.Node {
+		return ntree.New("0")
+	}},
+}
+
+func TestRewrite(t *testing.T) {
+	for _, order := range []ntree.RewriteOrder{ntree.BottomUp, ntree.TopDown} {
+		cases := []struct {
+			tree, expected string
+		}{
+			{"+(*(a 1) *(b 0))", "a"},
+			{"f(+(a 0) g(*(+(b 0) 1)) +(*(c 0) 0))", "f(a g(b) 0)"},
+			{"f(a b)", "f(a b)"},
+		}
+		for _, c := range cases {
+			root, err := ntree.Rewrite(parseLabels(c.tree), simplifyRules, ntree.RewriteStrategy{Order: order})
+			if err != nil {
+				t.Fatal("Rewrite failed:", err)
+			}
+			if s := formatLabels(root); s != c.expected {
+				t.Errorf("Rewriting %s gave %s but expected %s", c.tree, s, c.expected)
+			}
+			if !ntree.IsRoot(root) {
+				t.Error("Rewrite should return a root")
+			}
+		}
+	}
+}
+
+func TestRewriteAttachedSubtree(t *testing.T) {
+	for _, order := range []ntree.RewriteOrder{ntree.BottomUp, ntree.TopDown} {
+		tree := parseLabels("f(g(+(x 0)) y)")
+		sub := tree.Children.Children
+		root, err := ntree.Rewrite(sub, simplifyRules, ntree.RewriteStrategy{Order: order})
+		if err != nil {
+			t.Fatal("Rewrite failed:", err)
+		}
+		if formatLabels(root) != "x" || root.Parent != tree.Children {
+			t.Error("The rewritten subtree should take the place of the old one, got", formatLabels(tree))
+		}
+		if s := formatLabels(tree); s != "f(g(x) y)" {
+			t.Error("Rewrite should only change the subtree, got", s)
+		}
+	}
+}
+
+func TestRewriteWrap(t *testing.T) {
+	rules := []ntree.Rule{{"negate", ntree.Lit("x").AtDepth(2, 2), func(b ntree.Binding) *ntree.Node {
+		return ntree.Build("-", b.Node)
+	}}}
+	root, err := ntree.Rewrite(parseLabels("f(x y x)"), rules, ntree.RewriteStrategy{})
+	if err != nil {
+		t.Fatal("Rewrite failed:", err)
+	}
+	if s := formatLabels(root); s != "f(-(x) y -(x))" {
+		t.Error("Unexpected rewrite result", s)
+	}
+}
+
+func TestRewriteLimit(t *testing.T) {
+	rules := []ntree.Rule{
+		{"decline", ntree.Any(), func(b ntree.Binding) *ntree.Node {
+			return nil
+		}},
+		{"swap", ntree.Lit("+", ntree.Var("x"), ntree.Var("y")), func(b ntree.Binding) *ntree.Node {
+			return ntree.Build("+", b.Nodes["y"], b.Nodes["x"])
+		}},
+	}
+	root, err := ntree.Rewrite(parseLabels("+(a b)"), rules, ntree.RewriteStrategy{Order: ntree.TopDown, MaxPasses: 5})
+	if err != ntree.ErrRewriteLimit {
+		t.Error("Expected ErrRewriteLimit but got", err)
+	}
+	if s := formatLabels(root); s != "+(b a)" {
+		t.Error("Rewrite should return the tree after the last pass, got", s)
+	}
+}
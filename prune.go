@@ -0,0 +1,110 @@
+package ntree
+
+// nextSkipping returns the node after the subtree of n in a pre-order walk of root.
+func nextSkipping(root, n *Node) *Node {
+	for n != root {
+		if n.Next != nil {
+			return n.Next
+		}
+		n = n.Parent
+	}
+	return nil
+}
+
+// Prune unlinks every subtree below root whose top node matches pred and returns them in pre-order.  Nodes inside
+// a pruned subtree are not tested.
+func Prune(root *Node, pred func(*Node) bool) []*Node {
+	if root == nil || pred == nil {
+		return nil
+	}
+
+	var pruned []*Node
+	n := nextPreOrder(root, root)
+	for n != nil {
+		if !pred(n) {
+			n = nextPreOrder(root, n)
+			continue
+		}
+
+		next := nextSkipping(root, n)
+		Unlink(n)
+		pruned = append(pruned, n)
+		n = next
+	}
+	return pruned
+}
+
+// Filter returns a copy of the tree rooted at root that keeps the nodes matching pred and their ancestors, or nil
+// when nothing matches.  Values are copied by assignment.
+func Filter(root *Node, pred func(*Node) bool) *Node {
+	if root == nil || pred == nil {
+		return nil
+	}
+
+	copies := make(map[*Node]*Node)
+	Traverse(root, TraversePostOrder, TraverseAll, -1, func(n *Node, data interface{}) bool {
+		var kept []*Node
+		for child := n.Children; child != nil; child = child.Next {
+			if c, ok := copies[child]; ok {
+				kept = append(kept, c)
+				delete(copies, child)
+			}
+		}
+		if len(kept) == 0 && !pred(n) {
+			return false
+		}
+
+		c := New(n.Value)
+		for _, child := range kept {
+			AppendChild(c, child)
+		}
+		copies[n] = c
+		return false
+	}, nil)
+	return copies[root]
+}
+
+// CollapseChains merges every node below root that has a single child with that child, radix tree style, until
+// no such node is left.  merge returns the value of the merged node, and the child's children move up to it in
+// one splice.  Each merge raises a ValueChanged and a ChildRemoved event, and the moved children raise none.
+// CollapseChains returns the number of nodes removed.
+func CollapseChains(root *Node, merge func(parent, child interface{}) interface{}) int {
+	if root == nil || merge == nil {
+		return 0
+	}
+
+	removed := 0
+	for n := nextPreOrder(root, root); n != nil; n = nextPreOrder(root, n) {
+		for n.Children != nil && n.Children.Next == nil {
+			child := n.Children
+			SetValue(n, merge(n.Value, child.Value))
+			unlink(child)
+
+			// n has no other children, so the child's list becomes n's as it is.
+			n.Children, child.Children = child.Children, nil
+			for grandchild := n.Children; grandchild != nil; grandchild = grandchild.Next {
+				grandchild.Parent = n
+			}
+			touch(child)
+			notify(Event{Type: ChildRemoved, Node: n, Child: child}, n)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Flatten puts the children of n in n's place among its siblings, keeping their order, and unlinks n.
+func Flatten(n *Node) error {
+	if n == nil || n.Parent == nil {
+		return ErrInvalidNode
+	}
+
+	parent := n.Parent
+	for n.Children != nil {
+		child := n.Children
+		Unlink(child)
+		InsertBefore(parent, n, child)
+	}
+	Unlink(n)
+	return nil
+}
@@ -0,0 +1,116 @@
+package ntree_test
+
+import (
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func valueIs(values ...string) func(*ntree.Node) bool {
+	return func(n *ntree.Node) bool {
+		for _, v := range values {
+			if n.Value == v {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := GenerateStringTree()
+	pruned := ntree.Prune(root, valueIs("a", "a1", "b1x", ""))
+	if len(pruned) != 3 || pruned[0].Value != "a" || pruned[1].Value != "b1x" || pruned[2].Value != "" {
+		t.Fatal("Prune returned the wrong subtrees", pruned)
+	}
+	for _, n := range pruned {
+		if !ntree.IsRoot(n) {
+			t.Error("Pruned subtrees should be unlinked")
+		}
+	}
+	checkChildren(t, pruned[0], "a1", "a2")
+	checkChildren(t, root, "b")
+	checkChildren(t, root.Children.Children)
+
+	if ntree.Prune(root, valueIs("root")) != nil {
+		t.Error("Prune should not test the root")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	root := GenerateStringTree()
+	before := ntree.Copy(root)
+
+	filtered := ntree.Filter(root, valueIs("a2", "b1x"))
+	if !ntree.Equal(root, before, nil) {
+		t.Error("Filter should not change its input")
+	}
+	checkChildren(t, filtered, "a", "b")
+	checkChildren(t, filtered.Children, "a2")
+	checkChildren(t, filtered.Children.Next, "b1")
+	checkChildren(t, filtered.Children.Next.Children, "b1x")
+
+	filtered = ntree.Filter(root, valueIs("b"))
+	checkChildren(t, filtered, "b")
+	checkChildren(t, filtered.Children)
+
+	if ntree.Filter(root, valueIs("missing")) != nil {
+		t.Error("Filter should return nil when nothing matches")
+	}
+}
+
+func TestCollapseChains(t *testing.T) {
+	root := ntree.New("")
+	r := ntree.AppendChild(root, ntree.New("r"))
+	o := ntree.AppendChild(r, ntree.New("o"))
+	ntree.AppendChild(ntree.AppendChild(o, ntree.New("m")), ntree.New("e"))
+	u := ntree.AppendChild(o, ntree.New("u"))
+	ntree.AppendChild(ntree.AppendChild(u, ntree.New("b")), ntree.New("y"))
+	ntree.AppendChild(u, ntree.New("s"))
+	_, events := recordEvents(root, ntree.ObserveSubtree)
+
+	removed := ntree.CollapseChains(root, func(parent, child interface{}) interface{} {
+		return parent.(string) + child.(string)
+	})
+	if removed != 3 {
+		t.Error("Expected 3 removed nodes but got", removed)
+	}
+	if len(*events) != 6 {
+		t.Error("Each merge should raise a ValueChanged and a ChildRemoved event", *events)
+	}
+	checkChildren(t, root, "ro")
+	checkChildren(t, root.Children, "me", "u")
+	checkChildren(t, root.Children.Children.Next, "by", "s")
+
+	var values []string
+	ntree.Traverse(root, ntree.TraversePreOrder, ntree.TraverseLeaves, -1, func(n *ntree.Node, data interface{}) bool {
+		values = append(values, n.Value.(string))
+		return false
+	}, nil)
+	if strings.Join(values, ",") != "me,by,s" {
+		t.Error("Unexpected leaves after collapsing", values)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	root := GenerateStringTree()
+	a := root.Children
+
+	if err := ntree.Flatten(a); err != nil {
+		t.Fatal("Flatten failed:", err)
+	}
+	checkChildren(t, root, "a1", "a2", "b", "")
+	if !ntree.IsRoot(a) || a.Children != nil {
+		t.Error("Flattened node should be unlinked and empty")
+	}
+
+	if err := ntree.Flatten(root.Children.Next.Next); err != nil {
+		t.Fatal("Flatten failed:", err)
+	}
+	checkChildren(t, root, "a1", "a2", "b1", "")
+
+	if ntree.Flatten(root) != ntree.ErrInvalidNode {
+		t.Error("Flattening a root should fail")
+	}
+}
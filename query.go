@@ -0,0 +1,494 @@
+package ntree
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueryError reports a syntax error in a query.  Offset counts bytes from the start of the expression.
+type QueryError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("ntree: query: %s at offset %d", e.Msg, e.Offset)
+}
+
+// ValueMatcher reports whether n matches the literal of a value predicate such as [.='x'].
+type ValueMatcher func(n *Node, literal string) bool
+
+// QueryOptions configures a query.  Label names nodes for name tests and defaults to fmt.Sprint of the value.
+// Match decides value predicates and defaults to comparing the label with the literal.
+type QueryOptions struct {
+	Label LabelFunc
+	Match ValueMatcher
+}
+
+type queryAxis int
+
+const (
+	axisChild queryAxis = iota
+	axisDescendant
+	axisDescendantOrSelf
+	axisParent
+	axisAncestor
+	axisAncestorOrSelf
+	axisSelf
+	axisFollowingSibling
+	axisPrecedingSibling
+)
+
+var queryAxes = map[string]queryAxis{
+	"child":              axisChild,
+	"descendant":         axisDescendant,
+	"descendant-or-self": axisDescendantOrSelf,
+	"parent":             axisParent,
+	"ancestor":           axisAncestor,
+	"ancestor-or-self":   axisAncestorOrSelf,
+	"self":               axisSelf,
+	"following-sibling":  axisFollowingSibling,
+	"preceding-sibling":  axisPrecedingSibling,
+}
+
+type predicateKind int
+
+const (
+	predicatePosition predicateKind = iota
+	predicateLast
+	predicateValue
+)
+
+type queryPredicate struct {
+	kind    predicateKind
+	pos     int
+	literal string
+	negate  bool
+}
+
+type queryStep struct {
+	axis       queryAxis
+	name       string
+	wildcard   bool
+	predicates []queryPredicate
+}
+
+type queryPath struct {
+	absolute bool
+	steps    []queryStep
+}
+
+// Compiled is a parsed query that can be run any number of times.
+//
+// A query is one or more paths separated by |.  A path starting with / is evaluated from the root of the tree,
+// and other paths from the start node.  Steps are separated by / and // selects descendants.  A step is a name,
+// a quoted name, * for any node, . for the node itself, .. for its parent, or axis::test with one of the axes
+// child, descendant, descendant-or-self, parent, ancestor, ancestor-or-self, self, following-sibling and
+// preceding-sibling.  Steps may be followed by predicates: [n] for the nth node, counting from 1 along the axis,
+// [last()] for the last one, and [.='literal'] or [.!='literal'] to test values.
+type Compiled struct {
+	expr  string
+	paths []queryPath
+	opts  QueryOptions
+}
+
+// Query runs expr from root with the default options.
+func Query(root *Node, expr string) ([]*Node, error) {
+	c, err := Compile(expr, QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return c.Select(root), nil
+}
+
+// Compile parses expr.  Syntax errors are reported as a *QueryError.
+func Compile(expr string, opts QueryOptions) (*Compiled, error) {
+	if opts.Label == nil {
+		opts.Label = defaultLabel
+	}
+	if opts.Match == nil {
+		label := opts.Label
+		opts.Match = func(n *Node, literal string) bool {
+			return label(n) == literal
+		}
+	}
+
+	p := &queryParser{expr: expr}
+	paths, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Compiled{expr: expr, paths: paths, opts: opts}, nil
+}
+
+// String returns the expression the query was compiled from.
+func (c *Compiled) String() string {
+	return c.expr
+}
+
+// Select returns the nodes the query selects from start, without duplicates and in pre-order.
+func (c *Compiled) Select(start *Node) []*Node {
+	if start == nil {
+		return nil
+	}
+
+	seen := make(map[*Node]bool)
+	var result []*Node
+	for _, path := range c.paths {
+		context := []*Node{start}
+		if path.absolute {
+			root, _ := GetRoot(start)
+			context = []*Node{root}
+		}
+		for _, step := range path.steps {
+			context = c.step(context, step)
+		}
+		for _, n := range context {
+			if !seen[n] {
+				seen[n] = true
+				result = append(result, n)
+			}
+		}
+	}
+
+	root, _ := GetRoot(start)
+	order := make(map[*Node]int)
+	for n, i := root, 0; n != nil; n, i = nextPreOrder(root, n), i+1 {
+		if seen[n] {
+			order[n] = i
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return order[result[i]] < order[result[j]]
+	})
+	return result
+}
+
+// First returns the first node the query selects from start, or nil.
+func (c *Compiled) First(start *Node) *Node {
+	if nodes := c.Select(start); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+func (c *Compiled) step(context []*Node, step queryStep) []*Node {
+	seen := make(map[*Node]bool)
+	var result []*Node
+	for _, n := range context {
+		var nodes []*Node
+		for _, candidate := range axisNodes(n, step.axis) {
+			if step.wildcard || c.opts.Label(candidate) == step.name {
+				nodes = append(nodes, candidate)
+			}
+		}
+		for _, predicate := range step.predicates {
+			nodes = c.filter(nodes, predicate)
+		}
+
+		for _, node := range nodes {
+			if !seen[node] {
+				seen[node] = true
+				result = append(result, node)
+			}
+		}
+	}
+	return result
+}
+
+func (c *Compiled) filter(nodes []*Node, predicate queryPredicate) []*Node {
+	switch predicate.kind {
+	case predicatePosition:
+		if predicate.pos > len(nodes) {
+			return nil
+		}
+		return nodes[predicate.pos-1 : predicate.pos]
+	case predicateLast:
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[len(nodes)-1:]
+	}
+
+	var kept []*Node
+	for _, n := range nodes {
+		if c.opts.Match(n, predicate.literal) != predicate.negate {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// axisNodes returns the nodes along axis from n, nearest first for the reverse axes.
+func axisNodes(n *Node, axis queryAxis) []*Node {
+	var nodes []*Node
+	switch axis {
+	case axisChild:
+		for child := n.Children; child != nil; child = child.Next {
+			nodes = append(nodes, child)
+		}
+	case axisDescendant, axisDescendantOrSelf:
+		if axis == axisDescendantOrSelf {
+			nodes = append(nodes, n)
+		}
+		for d := nextPreOrder(n, n); d != nil; d = nextPreOrder(n, d) {
+			nodes = append(nodes, d)
+		}
+	case axisParent:
+		if n.Parent != nil {
+			nodes = append(nodes, n.Parent)
+		}
+	case axisAncestor, axisAncestorOrSelf:
+		if axis == axisAncestorOrSelf {
+			nodes = append(nodes, n)
+		}
+		for a := n.Parent; a != nil; a = a.Parent {
+			nodes = append(nodes, a)
+		}
+	case axisSelf:
+		nodes = append(nodes, n)
+	case axisFollowingSibling:
+		for s := n.Next; s != nil; s = s.Next {
+			nodes = append(nodes, s)
+		}
+	case axisPrecedingSibling:
+		for s := n.Previous; s != nil; s = s.Previous {
+			nodes = append(nodes, s)
+		}
+	}
+	return nodes
+}
+
+type queryParser struct {
+	expr string
+	pos  int
+}
+
+func (p *queryParser) errorf(offset int, format string, args ...interface{}) error {
+	return &QueryError{Offset: offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.expr) && (p.expr[p.pos] == ' ' || p.expr[p.pos] == '\t' || p.expr[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// accept consumes s if it comes next.
+func (p *queryParser) accept(s string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(s string) error {
+	if !p.accept(s) {
+		return p.unexpected("expected %q", s)
+	}
+	return nil
+}
+
+func (p *queryParser) unexpected(format string, args ...interface{}) error {
+	p.skipSpace()
+	if p.pos == len(p.expr) {
+		return p.errorf(p.pos, format+" but found end of query", args...)
+	}
+	return p.errorf(p.pos, format+" but found %q", append(args, p.expr[p.pos:p.pos+1])...)
+}
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *queryParser) name() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.expr) && isNameByte(p.expr[p.pos]) {
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+// literal reads a string quoted with ' or ", in which a backslash escapes the next byte.
+func (p *queryParser) literal() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos == len(p.expr) || (p.expr[p.pos] != '\'' && p.expr[p.pos] != '"') {
+		return "", p.unexpected("expected a quoted string")
+	}
+	quote := p.expr[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.expr):
+			b.WriteByte(p.expr[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf(start, "unterminated string")
+}
+
+func (p *queryParser) parse() ([]queryPath, error) {
+	var paths []queryPath
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		if !p.accept("|") {
+			break
+		}
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.expr) {
+		return nil, p.unexpected("expected / or |")
+	}
+	return paths, nil
+}
+
+var descendantOrSelfStep = queryStep{axis: axisDescendantOrSelf, wildcard: true}
+
+func (p *queryParser) path() (queryPath, error) {
+	var path queryPath
+	switch {
+	case p.accept("//"):
+		path.absolute = true
+		path.steps = append(path.steps, descendantOrSelfStep)
+	case p.accept("/"):
+		path.absolute = true
+		if p.atPathEnd() {
+			return path, nil
+		}
+	}
+
+	for {
+		step, err := p.step()
+		if err != nil {
+			return path, err
+		}
+		path.steps = append(path.steps, step)
+
+		if p.accept("//") {
+			path.steps = append(path.steps, descendantOrSelfStep)
+		} else if !p.accept("/") {
+			return path, nil
+		}
+	}
+}
+
+// atPathEnd reports whether nothing but a union or the end of the query follows, as after a lone /.
+func (p *queryParser) atPathEnd() bool {
+	p.skipSpace()
+	return p.pos == len(p.expr) || p.expr[p.pos] == '|'
+}
+
+func (p *queryParser) step() (queryStep, error) {
+	var step queryStep
+	switch {
+	case p.accept(".."):
+		step.axis, step.wildcard = axisParent, true
+	case p.accept("."):
+		step.axis, step.wildcard = axisSelf, true
+	default:
+		p.skipSpace()
+		start := p.pos
+		name := p.name()
+		if name != "" && p.accept("::") {
+			axis, ok := queryAxes[name]
+			if !ok {
+				return step, p.errorf(start, "unknown axis %q", name)
+			}
+			step.axis = axis
+			p.skipSpace()
+			start, name = p.pos, p.name()
+		}
+
+		switch {
+		case name != "":
+			step.name = name
+		case p.accept("*"):
+			step.wildcard = true
+		case p.pos < len(p.expr) && (p.expr[p.pos] == '\'' || p.expr[p.pos] == '"'):
+			literal, err := p.literal()
+			if err != nil {
+				return step, err
+			}
+			step.name = literal
+		default:
+			p.pos = start
+			return step, p.unexpected("expected a step")
+		}
+	}
+
+	for p.accept("[") {
+		predicate, err := p.predicate()
+		if err != nil {
+			return step, err
+		}
+		step.predicates = append(step.predicates, predicate)
+		if err := p.expect("]"); err != nil {
+			return step, err
+		}
+	}
+	return step, nil
+}
+
+func (p *queryParser) predicate() (queryPredicate, error) {
+	var predicate queryPredicate
+	p.skipSpace()
+	start := p.pos
+
+	if p.accept(".") {
+		switch {
+		case p.accept("!="):
+			predicate.negate = true
+		case p.accept("="):
+		default:
+			return predicate, p.unexpected("expected = or !=")
+		}
+		literal, err := p.literal()
+		if err != nil {
+			return predicate, err
+		}
+		predicate.kind, predicate.literal = predicateValue, literal
+		return predicate, nil
+	}
+
+	name := p.name()
+	if name == "last" {
+		if err := p.expect("("); err != nil {
+			return predicate, err
+		}
+		if err := p.expect(")"); err != nil {
+			return predicate, err
+		}
+		predicate.kind = predicateLast
+		return predicate, nil
+	}
+
+	pos, err := strconv.Atoi(name)
+	if err != nil || pos < 1 {
+		p.pos = start
+		if name != "" {
+			return predicate, p.errorf(start, "invalid predicate %q", name)
+		}
+		return predicate, p.unexpected("expected a position, last() or a value test")
+	}
+	predicate.kind, predicate.pos = predicatePosition, pos
+	return predicate, nil
+}
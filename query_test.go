@@ -0,0 +1,120 @@
+package ntree_test
+
+import (
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+func queryValues(t *testing.T, root *ntree.Node, expr string) string {
+	t.Helper()
+	nodes, err := ntree.Query(root, expr)
+	if err != nil {
+		t.Fatalf("Query %q failed: %v", expr, err)
+	}
+
+	var values []string
+	for _, n := range nodes {
+		values = append(values, n.Value.(string))
+	}
+	return strings.Join(values, ",")
+}
+
+func TestQuery(t *testing.T) {
+	root := parseLabels("r(a(x(y) z) b(x(y)) c(x x))")
+	cases := []struct {
+		expr, expected string
+	}{
+		{"/", "r"},
+		{"/a", "a"},
+		{"a/x", "x"},
+		{"*/x", "x,x,x,x"},
+		{"//y", "y,y"},
+		{"/a//*", "x,y,z"},
+		{"//x[1]", "x,x,x"},
+		{"c/x[last()]", "x"},
+		{"*[2]", "b"},
+		{"*[last()]/x", "x,x"},
+		{"//y/..", "x,x"},
+		{"//y/ancestor::*", "r,a,x,b,x"},
+		{"//y/ancestor::*[1]", "x,x"},
+		{"//z/preceding-sibling::*", "x"},
+		{"a/following-sibling::*[1]", "b"},
+		{"a | c | b", "a,b,c"},
+		{"//*[.='x'][2]", "x"},
+		{"*[.!='b']", "a,c"},
+		{"descendant::*[.= 'z'] | self::r", "r,z"},
+		{"./a/./x/'y'", "y"},
+		{"//missing", ""},
+		{"a/x[3]", ""},
+	}
+	for _, c := range cases {
+		if values := queryValues(t, root, c.expr); values != c.expected {
+			t.Errorf("Query %q gave %q but expected %q", c.expr, values, c.expected)
+		}
+	}
+
+	if values := queryValues(t, root.Children.Next, "/c | x | ../a"); values != "a,x,c" {
+		t.Error("Relative queries should start at the given node, got", values)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	cases := []struct {
+		expr   string
+		offset int
+	}{
+		{"", 0},
+		{"a/", 2},
+		{"a[", 2},
+		{"a[0]", 2},
+		{"a[x]", 2},
+		{"bogus::a", 0},
+		{"a | 'b", 4},
+		{"a[last(]", 7},
+		{"a[.~'x']", 3},
+		{"a]", 1},
+	}
+	for _, c := range cases {
+		_, err := ntree.Compile(c.expr, ntree.QueryOptions{})
+		qe, ok := err.(*ntree.QueryError)
+		if !ok {
+			t.Errorf("Expected a QueryError for %q but got %v", c.expr, err)
+			continue
+		}
+		if qe.Offset != c.offset {
+			t.Errorf("Expected an error at offset %d for %q but got %v", c.offset, c.expr, qe)
+		}
+	}
+}
+
+func TestCompiledOptions(t *testing.T) {
+	root := GenerateTree()["root"]
+	c, err := ntree.Compile("//*[.='a_1']/*[last()]", ntree.QueryOptions{
+		Label: func(n *ntree.Node) string {
+			return n.Value.(*MockData).Id
+		},
+	})
+	if err != nil {
+		t.Fatal("Compile failed:", err)
+	}
+	if n := c.First(root); n == nil || n.Value.(*MockData).Id != "a_1_3" {
+		t.Error("Query with a custom label gave", n)
+	}
+
+	prefix, err := ntree.Compile("//*[.='a_1']", ntree.QueryOptions{
+		Match: func(n *ntree.Node, literal string) bool {
+			return strings.HasPrefix(n.Value.(*MockData).Id, literal)
+		},
+	})
+	if err != nil {
+		t.Fatal("Compile failed:", err)
+	}
+	if nodes := prefix.Select(root); len(nodes) != 4 {
+		t.Error("Query with a custom matcher should select a_1 and its children, got", len(nodes))
+	}
+	if prefix.String() != "//*[.='a_1']" {
+		t.Error("String should return the expression")
+	}
+}
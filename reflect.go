@@ -0,0 +1,421 @@
+package ntree
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReflectValue is the Value FromValue gives each node.  Label is the field name, map key or element index that
+// leads to the node, Key holds the original map key for map entries, and Value holds the scalar for leaves.  Cycle
+// is set on a node whose pointer, map or slice was already being visited further up the tree; it has no children.
+type ReflectValue struct {
+	Label string
+	Key   interface{}
+	Kind  reflect.Kind
+	Value interface{}
+	Cycle bool
+}
+
+// ReflectOptions configures FromValue and ToValue.  TagName defaults to "ntree"; the tag holds the label followed
+// by options, where "omitempty" skips zero fields and a label of "-" skips the field.  Unexported fields are
+// always skipped.
+type ReflectOptions struct {
+	TagName string
+}
+
+// FromValue builds a tree mirroring v: a node for each struct field, map entry and slice or array element, with
+// pointers and interfaces followed transparently.  Structs implementing encoding.TextMarshaler, such as
+// time.Time, become leaves holding their text instead; other structs without exported fields become empty nodes.
+func FromValue(v interface{}, opts ReflectOptions) *Node {
+	b := reflectBuilder{opts: opts, visiting: make(map[visitKey]bool)}
+	return b.build(reflect.ValueOf(v), &ReflectValue{})
+}
+
+// visitKey identifies a pointer, map or slice being visited.  The type is part of the key because a slice and
+// a pointer to its first element share an address.
+type visitKey struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+type reflectBuilder struct {
+	opts     ReflectOptions
+	visiting map[visitKey]bool
+}
+
+// enter marks v as being visited, returning false if it already is.
+func (b *reflectBuilder) enter(v reflect.Value) (visitKey, bool) {
+	key := visitKey{v.Pointer(), v.Type()}
+	if b.visiting[key] {
+		return key, false
+	}
+	b.visiting[key] = true
+	return key, true
+}
+
+func (b *reflectBuilder) build(v reflect.Value, rv *ReflectValue) *Node {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			rv.Kind = v.Kind()
+			return New(rv)
+		}
+		if v.Kind() == reflect.Ptr {
+			key, ok := b.enter(v)
+			if !ok {
+				rv.Kind, rv.Cycle = v.Kind(), true
+				return New(rv)
+			}
+			defer delete(b.visiting, key)
+		}
+		v = v.Elem()
+	}
+
+	n := New(rv)
+	if !v.IsValid() {
+		return n
+	}
+	rv.Kind = v.Kind()
+
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && !v.IsNil() {
+		key, ok := b.enter(v)
+		if !ok {
+			rv.Cycle = true
+			return n
+		}
+		defer delete(b.visiting, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if text, ok := marshalText(v); ok {
+			rv.Value = text
+			break
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			label, omitEmpty, ok := fieldLabel(t.Field(i), b.opts)
+			if !ok || omitEmpty && v.Field(i).IsZero() {
+				continue
+			}
+			AppendChild(n, b.build(v.Field(i), &ReflectValue{Label: label}))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			k := key.Interface()
+			AppendChild(n, b.build(v.MapIndex(key), &ReflectValue{Label: fmt.Sprint(k), Key: k}))
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			rv.Value = v.Interface()
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			AppendChild(n, b.build(v.Index(i), &ReflectValue{Label: strconv.Itoa(i)}))
+		}
+	default:
+		if v.CanInterface() {
+			rv.Value = v.Interface()
+		}
+	}
+
+	return n
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// marshalText returns the text of v if it implements encoding.TextMarshaler, with a value or pointer receiver.
+func marshalText(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	if !v.Type().Implements(textMarshalerType) {
+		if !reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+			return "", false
+		}
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", false
+	}
+	return string(text), true
+}
+
+func fieldLabel(f reflect.StructField, opts ReflectOptions) (string, bool, bool) {
+	if f.PkgPath != "" {
+		return "", false, false
+	}
+
+	tagName := opts.TagName
+	if tagName == "" {
+		tagName = "ntree"
+	}
+	parts := strings.Split(f.Tag.Get(tagName), ",")
+	if parts[0] == "-" && len(parts) == 1 {
+		return "", false, false
+	}
+
+	label := parts[0]
+	if label == "" {
+		label = f.Name
+	}
+	omitEmpty := false
+	for _, option := range parts[1:] {
+		omitEmpty = omitEmpty || option == "omitempty"
+	}
+	return label, omitEmpty, true
+}
+
+// ToValue fills the value out points to from a tree with the shape FromValue produces.  Struct fields are matched
+// by label, map entries use Key (or Label when Key is nil), and slices are resized to the number of children.
+// Maps and slices without children are left nil.  Interfaces receive a map[string]interface{} for structs and
+// maps, and an []interface{} for slices and arrays.
+func ToValue(root *Node, out interface{}, opts ReflectOptions) error {
+	v := reflect.ValueOf(out)
+	if root == nil || v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("ntree: ToValue needs a root and a non-nil pointer")
+	}
+	return fillValue(root, v.Elem(), opts)
+}
+
+func fillValue(n *Node, v reflect.Value, opts ReflectOptions) error {
+	rv, ok := n.Value.(*ReflectValue)
+	if !ok {
+		return fmt.Errorf("ntree: node value is %T, not *ReflectValue", n.Value)
+	}
+	if rv.Cycle {
+		return fmt.Errorf("ntree: cannot fill %q from a cyclic reference", rv.Label)
+	}
+
+	if (rv.Kind == reflect.Ptr || rv.Kind == reflect.Interface) && n.Children == nil && rv.Value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return fillValue(n, v.Elem(), opts)
+	}
+	if v.Kind() == reflect.Interface && rv.Value == nil {
+		if t := dynamicType(rv.Kind); t != nil {
+			if !t.AssignableTo(v.Type()) {
+				return fmt.Errorf("ntree: cannot fill %s from a %s", v.Type(), rv.Kind)
+			}
+			elem := reflect.New(t).Elem()
+			if err := fillValue(n, elem, opts); err != nil {
+				return err
+			}
+			v.Set(elem)
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if text, ok := rv.Value.(string); ok && v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		}
+		fields := make(map[string]int)
+		for i := 0; i < v.NumField(); i++ {
+			if label, _, ok := fieldLabel(v.Type().Field(i), opts); ok {
+				fields[label] = i
+			}
+		}
+		for child := n.Children; child != nil; child = child.Next {
+			i, ok := fields[labelOf(child)]
+			if !ok {
+				return fmt.Errorf("ntree: no field %q in %s", labelOf(child), v.Type())
+			}
+			if err := fillValue(child, v.Field(i), opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.IsNil() && n.Children != nil {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for child := n.Children; child != nil; child = child.Next {
+			key, err := mapKey(child, v.Type().Key())
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := fillValue(child, elem, opts); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		if rv.Value != nil {
+			return assignValue(rv, v)
+		}
+		count := 0
+		for child := n.Children; child != nil; child = child.Next {
+			count++
+		}
+		if v.Kind() == reflect.Slice && count == 0 {
+			v.Set(reflect.Zero(v.Type()))
+		} else if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), count, count))
+		} else if count > v.Len() {
+			return fmt.Errorf("ntree: %d elements do not fit in %s", count, v.Type())
+		}
+		i := 0
+		for child := n.Children; child != nil; child = child.Next {
+			if err := fillValue(child, v.Index(i), opts); err != nil {
+				return err
+			}
+			i++
+		}
+		return nil
+	}
+
+	if n.Children != nil {
+		return fmt.Errorf("ntree: cannot fill %s from a node with children", v.Type())
+	}
+	return assignValue(rv, v)
+}
+
+var (
+	dynamicMapType   = reflect.TypeOf(map[string]interface{}{})
+	dynamicSliceType = reflect.TypeOf([]interface{}{})
+)
+
+// dynamicType returns the type ToValue stores in an interface for a node of the given kind, or nil for scalars.
+func dynamicType(kind reflect.Kind) reflect.Type {
+	switch kind {
+	case reflect.Struct, reflect.Map:
+		return dynamicMapType
+	case reflect.Slice, reflect.Array:
+		return dynamicSliceType
+	}
+	return nil
+}
+
+func labelOf(n *Node) string {
+	if rv, ok := n.Value.(*ReflectValue); ok {
+		return rv.Label
+	}
+	return ""
+}
+
+func mapKey(n *Node, t reflect.Type) (reflect.Value, error) {
+	rv := n.Value.(*ReflectValue)
+	var key interface{} = rv.Label
+	if rv.Key != nil {
+		key = rv.Key
+	}
+
+	k := reflect.ValueOf(key)
+	if t.Kind() == reflect.String && k.Kind() != reflect.String {
+		// Use the formatted key rather than converting numbers to runes.
+		k = reflect.ValueOf(rv.Label)
+	}
+	k, err := convertValue(k, t)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("ntree: map key %v: %v", key, err)
+	}
+	return k, nil
+}
+
+func assignValue(rv *ReflectValue, v reflect.Value) error {
+	if rv.Value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	value, err := convertValue(reflect.ValueOf(rv.Value), v.Type())
+	if err != nil {
+		return err
+	}
+	v.Set(value)
+	return nil
+}
+
+// convertValue converts value to t, refusing to turn numbers into strings and numeric conversions that overflow
+// or drop a fraction.
+func convertValue(value reflect.Value, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case value.Type().AssignableTo(t):
+		return value, nil
+	case !value.Type().ConvertibleTo(t) || t.Kind() == reflect.String && value.Kind() != reflect.String:
+		return reflect.Value{}, fmt.Errorf("ntree: cannot assign %s to %s", value.Type(), t)
+	case !fits(value, t):
+		return reflect.Value{}, fmt.Errorf("ntree: %v does not fit in %s", value.Interface(), t)
+	}
+	return value.Convert(t), nil
+}
+
+type numberKind int
+
+const (
+	notNumber numberKind = iota
+	signedNumber
+	unsignedNumber
+	floatNumber
+)
+
+func numberKindOf(k reflect.Kind) numberKind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return signedNumber
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return unsignedNumber
+	case reflect.Float32, reflect.Float64:
+		return floatNumber
+	}
+	return notNumber
+}
+
+// fits reports whether the number value keeps its value when converted to t.
+func fits(value reflect.Value, t reflect.Type) bool {
+	target := reflect.New(t).Elem()
+	switch numberKindOf(value.Kind()) {
+	case signedNumber:
+		i := value.Int()
+		switch numberKindOf(t.Kind()) {
+		case signedNumber:
+			return !target.OverflowInt(i)
+		case unsignedNumber:
+			return i >= 0 && !target.OverflowUint(uint64(i))
+		}
+	case unsignedNumber:
+		u := value.Uint()
+		switch numberKindOf(t.Kind()) {
+		case signedNumber:
+			return u <= math.MaxInt64 && !target.OverflowInt(int64(u))
+		case unsignedNumber:
+			return !target.OverflowUint(u)
+		}
+	case floatNumber:
+		f := value.Float()
+		switch numberKindOf(t.Kind()) {
+		case signedNumber:
+			return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && !target.OverflowInt(int64(f))
+		case unsignedNumber:
+			return f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !target.OverflowUint(uint64(f))
+		case floatNumber:
+			return !target.OverflowFloat(f)
+		}
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+package ntree_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+type reflectServer struct {
+	Host    string `ntree:"host"`
+	Port    int    `ntree:"port,omitempty"`
+	Tags    []string
+	Labels  map[string]string
+	Backup  *reflectServer
+	Secret  string `ntree:"-"`
+	private int
+}
+
+type reflectConfig struct {
+	Name    string
+	Servers []reflectServer
+	Weights map[int]float64
+	Extra   interface{}
+	Raw     []byte
+}
+
+func findLabel(root *ntree.Node, path ...string) *ntree.Node {
+	n := root
+	for _, label := range path {
+		child := n.Children
+		for child != nil && child.Value.(*ntree.ReflectValue).Label != label {
+			child = child.Next
+		}
+		if child == nil {
+			return nil
+		}
+		n = child
+	}
+	return n
+}
+
+func TestFromValue(t *testing.T) {
+	config := reflectConfig{
+		Name: "prod",
+		Servers: []reflectServer{
+			{Host: "a", Port: 80, Tags: []string{"x", "y"}, Secret: "s", private: 1},
+			{Host: "b", Labels: map[string]string{"zone": "1", "rack": "2"}},
+		},
+		Weights: map[int]float64{2: 0.5, 1: 1.5},
+		Extra:   "extra",
+	}
+
+	root := ntree.FromValue(&config, ntree.ReflectOptions{})
+	if root.Value.(*ntree.ReflectValue).Kind != reflect.Struct {
+		t.Error("Root should be the struct behind the pointer")
+	}
+
+	if n := findLabel(root, "Servers", "0", "host"); n == nil || n.Value.(*ntree.ReflectValue).Value != "a" {
+		t.Error("Struct tag labels should be used")
+	}
+	if findLabel(root, "Servers", "1", "port") != nil {
+		t.Error("omitempty fields should be skipped when zero")
+	}
+	if findLabel(root, "Servers", "0", "Secret") != nil || findLabel(root, "Servers", "0", "private") != nil {
+		t.Error("Skipped and unexported fields should not become nodes")
+	}
+	if n := findLabel(root, "Servers", "1", "Labels"); n == nil || n.Children.Value.(*ntree.ReflectValue).Label != "rack" {
+		t.Error("Map entries should be sorted by key")
+	}
+	if n := findLabel(root, "Weights", "2"); n == nil || n.Value.(*ntree.ReflectValue).Key != 2 || n.Value.(*ntree.ReflectValue).Value != 0.5 {
+		t.Error("Map keys should be kept")
+	}
+	if n := findLabel(root, "Extra"); n == nil || n.Value.(*ntree.ReflectValue).Value != "extra" {
+		t.Error("Interfaces should be followed")
+	}
+
+	if ntree.FromValue(nil, ntree.ReflectOptions{}) == nil {
+		t.Error("FromValue(nil) should still return a node")
+	}
+}
+
+func TestFromValueCycles(t *testing.T) {
+	server := &reflectServer{Host: "loop"}
+	server.Backup = server
+
+	root := ntree.FromValue(server, ntree.ReflectOptions{})
+	backup := findLabel(root, "Backup")
+	if backup == nil || !backup.Value.(*ntree.ReflectValue).Cycle || backup.Children != nil {
+		t.Error("Pointer cycle should be cut")
+	}
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	s := []interface{}{nil}
+	s[0] = s
+	if n := findLabel(ntree.FromValue(m, ntree.ReflectOptions{}), "self"); n == nil || !n.Value.(*ntree.ReflectValue).Cycle {
+		t.Error("Map cycle should be cut")
+	}
+	if n := findLabel(ntree.FromValue(s, ntree.ReflectOptions{}), "0"); n == nil || !n.Value.(*ntree.ReflectValue).Cycle {
+		t.Error("Slice cycle should be cut")
+	}
+
+	shared := &reflectServer{Host: "shared"}
+	pair := []*reflectServer{shared, shared}
+	if n := findLabel(ntree.FromValue(pair, ntree.ReflectOptions{}), "1", "host"); n == nil {
+		t.Error("Shared pointers that are not cycles should be visited twice")
+	}
+
+	if ntree.ToValue(root, &reflectServer{}, ntree.ReflectOptions{}) == nil {
+		t.Error("ToValue should fail for a cyclic tree")
+	}
+}
+
+func TestToValue(t *testing.T) {
+	config := reflectConfig{
+		Name: "prod",
+		Servers: []reflectServer{
+			{Host: "a", Port: 80, Tags: []string{"x", "y"}, Backup: &reflectServer{Host: "c"}},
+			{Host: "b", Labels: map[string]string{"zone": "1"}},
+		},
+		Weights: map[int]float64{1: 1.5},
+		Extra:   42,
+		Raw:     []byte("raw"),
+	}
+
+	root := ntree.FromValue(config, ntree.ReflectOptions{})
+	out := reflectConfig{}
+	if err := ntree.ToValue(root, &out, ntree.ReflectOptions{}); err != nil {
+		t.Fatal("ToValue failed:", err)
+	}
+	if !reflect.DeepEqual(config, out) {
+		t.Errorf("Expected %+v but got %+v", config, out)
+	}
+
+	if ntree.ToValue(root, out, ntree.ReflectOptions{}) == nil || ntree.ToValue(nil, &out, ntree.ReflectOptions{}) == nil {
+		t.Error("ToValue should fail without a root or a pointer")
+	}
+	if ntree.ToValue(root, &reflectServer{}, ntree.ReflectOptions{}) == nil {
+		t.Error("ToValue should fail for a tree of a different shape")
+	}
+	if ntree.ToValue(ntree.New(&ntree.ReflectValue{Value: 5}), new(string), ntree.ReflectOptions{}) == nil {
+		t.Error("ToValue should not convert numbers to strings")
+	}
+}
+
+func TestToValueInterfaces(t *testing.T) {
+	config := reflectConfig{
+		Extra: map[string]interface{}{
+			"nested": map[string]interface{}{"k": []int{1, 2}, "name": "x"},
+			"server": reflectServer{Host: "a", Port: 80},
+			"empty":  nil,
+		},
+	}
+	expected := reflectConfig{
+		Extra: map[string]interface{}{
+			"nested": map[string]interface{}{"k": []interface{}{1, 2}, "name": "x"},
+			"server": map[string]interface{}{"host": "a", "port": 80, "Tags": []interface{}(nil),
+				"Labels": map[string]interface{}(nil), "Backup": nil},
+			"empty": nil,
+		},
+	}
+
+	root := ntree.FromValue(config, ntree.ReflectOptions{})
+	out := reflectConfig{}
+	if err := ntree.ToValue(root, &out, ntree.ReflectOptions{}); err != nil {
+		t.Fatal("ToValue failed:", err)
+	}
+	if !reflect.DeepEqual(expected, out) {
+		t.Errorf("Expected %+v but got %+v", expected, out)
+	}
+
+	var stringer interface{ String() string }
+	if ntree.ToValue(ntree.FromValue([]int{1}, ntree.ReflectOptions{}), &stringer, ntree.ReflectOptions{}) == nil {
+		t.Error("ToValue should fail for interfaces a slice does not implement")
+	}
+}
+
+func TestToValueConversions(t *testing.T) {
+	keys := map[string]string{}
+	if err := ntree.ToValue(ntree.FromValue(map[int]string{65: "a"}, ntree.ReflectOptions{}), &keys, ntree.ReflectOptions{}); err != nil || keys["65"] != "a" {
+		t.Error("Integer map keys should be formatted, not converted to runes", keys, err)
+	}
+
+	var i int
+	var i8 int8
+	var u uint
+	var f32 float32
+	lossy := []struct {
+		value interface{}
+		out   interface{}
+	}{
+		{1.9, &i},
+		{300, &i8},
+		{-1, &u},
+		{uint64(1 << 63), &i},
+		{1e300, &f32},
+	}
+	for _, c := range lossy {
+		if ntree.ToValue(ntree.FromValue(c.value, ntree.ReflectOptions{}), c.out, ntree.ReflectOptions{}) == nil {
+			t.Errorf("Converting %v to %T should fail", c.value, c.out)
+		}
+	}
+	if err := ntree.ToValue(ntree.FromValue(2.0, ntree.ReflectOptions{}), &i8, ntree.ReflectOptions{}); err != nil || i8 != 2 {
+		t.Error("Whole floats that fit should convert", i8, err)
+	}
+}
+
+func TestReflectTextMarshaler(t *testing.T) {
+	type event struct {
+		At   time.Time
+		Name string
+	}
+	in := event{At: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Name: "x"}
+	root := ntree.FromValue(in, ntree.ReflectOptions{})
+	at := findLabel(root, "At")
+	if at.Children != nil || at.Value.(*ntree.ReflectValue).Value != "2020-01-02T03:04:05Z" {
+		t.Error("Text marshalers should become leaves holding their text", at.Value)
+	}
+
+	out := event{}
+	if err := ntree.ToValue(root, &out, ntree.ReflectOptions{}); err != nil || !out.At.Equal(in.At) || out.Name != "x" {
+		t.Errorf("Expected %+v but got %+v (%v)", in, out, err)
+	}
+}
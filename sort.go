@@ -0,0 +1,101 @@
+package ntree
+
+// LessFunc reports whether the value a sorts before the value b.
+type LessFunc func(a, b interface{}) bool
+
+// SortChildren stably sorts the children of parent by their values.  It relinks the existing nodes with a bottom-up
+// merge sort and allocates nothing, and raises a Sorted event.
+func SortChildren(parent *Node, less LessFunc) {
+	if parent == nil || less == nil || parent.Children == nil {
+		return
+	}
+
+	touch(parent)
+	parent.Children = mergeSortSiblings(parent.Children, less)
+
+	var previous *Node
+	for child := parent.Children; child != nil; child = child.Next {
+		child.Previous = previous
+		previous = child
+	}
+	notify(Event{Type: Sorted, Node: parent}, parent)
+}
+
+// mergeSortSiblings sorts the list starting at head by Next links only, taking runs of width 1, 2, 4, ... and
+// merging neighbouring runs until a single run is left.
+func mergeSortSiblings(head *Node, less LessFunc) *Node {
+	for width := 1; ; width *= 2 {
+		var sorted, tail *Node
+		merges := 0
+
+		p := head
+		for p != nil {
+			merges++
+			q := p
+			pSize := 0
+			for pSize < width && q != nil {
+				pSize++
+				q = q.Next
+			}
+			qSize := width
+
+			for pSize > 0 || (qSize > 0 && q != nil) {
+				var next *Node
+				switch {
+				case pSize == 0:
+					next, q = q, q.Next
+					qSize--
+				case qSize == 0 || q == nil || !less(q.Value, p.Value):
+					next, p = p, p.Next
+					pSize--
+				default:
+					next, q = q, q.Next
+					qSize--
+				}
+
+				if tail == nil {
+					sorted = next
+				} else {
+					tail.Next = next
+				}
+				tail = next
+			}
+			p = q
+		}
+		tail.Next = nil
+
+		head = sorted
+		if merges <= 1 {
+			return head
+		}
+	}
+}
+
+// SortTree sorts the children of root, and of every node below it when recursive is set.
+func SortTree(root *Node, less LessFunc, recursive bool) {
+	if root == nil || less == nil {
+		return
+	}
+	if !recursive {
+		SortChildren(root, less)
+		return
+	}
+
+	for n := root; n != nil; n = nextPreOrder(root, n) {
+		SortChildren(n, less)
+	}
+}
+
+// InsertSorted inserts n among the children of parent after every child that does not sort after it, so that
+// children added this way stay sorted.
+func InsertSorted(parent, n *Node, less LessFunc) *Node {
+	if parent == nil || n == nil || less == nil || !IsRoot(n) {
+		return nil
+	}
+
+	sibling := parent.Children
+	for sibling != nil && !less(n.Value, sibling.Value) {
+		sibling = sibling.Next
+	}
+	return InsertBefore(parent, sibling, n)
+}
@@ -0,0 +1,96 @@
+package ntree_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+type sortItem struct {
+	Key   int
+	Order int
+}
+
+func lessByKey(a, b interface{}) bool {
+	return a.(*sortItem).Key < b.(*sortItem).Key
+}
+
+func TestSortChildren(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	for _, size := range []int{0, 1, 2, 3, 7, 64, 1000} {
+		parent := ntree.New(nil)
+		var items []*sortItem
+		for i := 0; i < size; i++ {
+			item := &sortItem{r.Intn(10), i}
+			items = append(items, item)
+			ntree.AppendChild(parent, ntree.New(item))
+		}
+
+		ntree.SortChildren(parent, lessByKey)
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Key < items[j].Key
+		})
+
+		i := 0
+		var previous *ntree.Node
+		for child := parent.Children; child != nil; child = child.Next {
+			if child.Value != items[i] {
+				t.Fatalf("Sort of %d children is wrong or unstable at %d", size, i)
+			}
+			if child.Previous != previous || child.Parent != parent {
+				t.Fatal("Broken links after sorting")
+			}
+			previous = child
+			i++
+		}
+		if i != size {
+			t.Fatalf("Expected %d children after sorting but got %d", size, i)
+		}
+	}
+}
+
+func TestSortTree(t *testing.T) {
+	less := func(a, b interface{}) bool {
+		return a.(string) < b.(string)
+	}
+
+	root := GenerateStringTree()
+	reverseChildren(root)
+	ntree.SortTree(root, less, false)
+	checkChildren(t, root, "", "a", "b")
+	checkChildren(t, root.Children.Next, "a2", "a1")
+
+	ntree.SortTree(root, less, true)
+	checkChildren(t, root.Children.Next, "a1", "a2")
+
+	checkChildren(t, root, "", "a", "b")
+}
+
+func TestInsertSorted(t *testing.T) {
+	parent := ntree.New(nil)
+	var items []*sortItem
+	for i, key := range []int{5, 1, 3, 5, 1, 9, 0} {
+		item := &sortItem{key, i}
+		items = append(items, item)
+		if ntree.InsertSorted(parent, ntree.New(item), lessByKey) == nil {
+			t.Fatal("InsertSorted failed")
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	i := 0
+	for child := parent.Children; child != nil; child = child.Next {
+		if child.Value != items[i] {
+			t.Fatal("Children inserted with InsertSorted should be sorted and stable")
+		}
+		i++
+	}
+
+	if ntree.InsertSorted(parent, parent.Children, lessByKey) != nil || ntree.InsertSorted(nil, ntree.New(nil), lessByKey) != nil {
+		t.Error("InsertSorted should reject non-root nodes and a nil parent")
+	}
+}
@@ -0,0 +1,150 @@
+package ntree
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrTxDone = errors.New("ntree: transaction already committed or rolled back")
+
+// Tx batches changes to a tree so that they can be rolled back together.  Changes are made at once, through the
+// methods of the Tx, and raise their events as they happen; Rollback reverses them in order, restoring every
+// parent, sibling order and Children head.  Changes made around the Tx are not undone.
+type Tx struct {
+	root    *Node
+	journal *Journal
+}
+
+// Begin starts a transaction on the tree rooted at root.
+func Begin(root *Node) *Tx {
+	j := NewJournal(0)
+	j.Begin("")
+	return &Tx{root: root, journal: j}
+}
+
+// Root returns the root the transaction was started on.
+func (tx *Tx) Root() *Node {
+	return tx.root
+}
+
+// AppendChild is like AppendChild but part of the transaction.
+func (tx *Tx) AppendChild(parent, n *Node) *Node {
+	if tx.journal == nil {
+		return nil
+	}
+	return tx.journal.AppendChild(parent, n)
+}
+
+// InsertBefore is like InsertBefore but part of the transaction.
+func (tx *Tx) InsertBefore(parent, sibling, n *Node) *Node {
+	if tx.journal == nil {
+		return nil
+	}
+	return tx.journal.InsertBefore(parent, sibling, n)
+}
+
+// Unlink is like Unlink but part of the transaction.
+func (tx *Tx) Unlink(n *Node) {
+	if tx.journal != nil {
+		tx.journal.Unlink(n)
+	}
+}
+
+// Move is like Move but part of the transaction.
+func (tx *Tx) Move(n, newParent *Node, position int) error {
+	if tx.journal == nil {
+		return ErrTxDone
+	}
+	return tx.journal.Move(n, newParent, position)
+}
+
+// SwapSiblings is like SwapSiblings but part of the transaction.
+func (tx *Tx) SwapSiblings(a, b *Node) error {
+	if tx.journal == nil {
+		return ErrTxDone
+	}
+	return tx.journal.SwapSiblings(a, b)
+}
+
+// SwapSubtrees is like SwapSubtrees but part of the transaction.
+func (tx *Tx) SwapSubtrees(a, b *Node) error {
+	if tx.journal == nil {
+		return ErrTxDone
+	}
+	return tx.journal.SwapSubtrees(a, b)
+}
+
+// ReplaceNode is like ReplaceNode but part of the transaction.
+func (tx *Tx) ReplaceNode(old, replacement *Node) error {
+	if tx.journal == nil {
+		return ErrTxDone
+	}
+	return tx.journal.ReplaceNode(old, replacement)
+}
+
+// SetValue is like SetValue but part of the transaction.
+func (tx *Tx) SetValue(n *Node, v interface{}) {
+	if tx.journal != nil {
+		tx.journal.SetValue(n, v)
+	}
+}
+
+// Commit keeps the changes and ends the transaction.
+func (tx *Tx) Commit() error {
+	if tx.journal == nil {
+		return ErrTxDone
+	}
+	tx.journal = nil
+	return nil
+}
+
+// Rollback reverses the changes and ends the transaction.
+func (tx *Tx) Rollback() error {
+	if tx.journal == nil {
+		return ErrTxDone
+	}
+	err := tx.journal.Rollback()
+	tx.journal = nil
+	return err
+}
+
+// SyncTree guards a tree with a read-write lock.  Readers only ever see the tree as it was before or after a whole
+// Update.
+type SyncTree struct {
+	mu   sync.RWMutex
+	root *Node
+}
+
+// NewSyncTree returns a SyncTree guarding the tree rooted at root, which must only be used through it from then on.
+func NewSyncTree(root *Node) *SyncTree {
+	return &SyncTree{root: root}
+}
+
+// View calls fn with the root while holding the read lock.  fn must not change the tree.
+func (s *SyncTree) View(fn func(root *Node) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.root)
+}
+
+// Update calls fn with a transaction on the tree while holding the write lock.  The transaction is committed when
+// fn returns nil, unless fn ended it, and rolled back when fn returns an error or panics.
+func (s *SyncTree) Update(fn func(tx *Tx) error) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := Begin(s.root)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
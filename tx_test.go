@@ -0,0 +1,120 @@
+package ntree_test
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+type links struct {
+	parent, next, previous, children *ntree.Node
+	value                            interface{}
+}
+
+func snapshotLinks(nodes []*ntree.Node) map[*ntree.Node]links {
+	snapshot := make(map[*ntree.Node]links)
+	for _, n := range nodes {
+		snapshot[n] = links{n.Parent, n.Next, n.Previous, n.Children, n.Value}
+	}
+	return snapshot
+}
+
+func TestTxRollback(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for round := 0; round < 50; round++ {
+		nodes := GenerateRandomTree(r, 30)
+		before := snapshotLinks(nodes)
+
+		tx := ntree.Begin(nodes[0])
+		for i := 0; i < 60; i++ {
+			n, other := nodes[r.Intn(len(nodes))], nodes[r.Intn(len(nodes))]
+			switch r.Intn(5) {
+			case 0:
+				tx.AppendChild(n, ntree.New(-i))
+			case 1:
+				if n.Parent != nil {
+					tx.InsertBefore(n.Parent, n, ntree.New(-i))
+				}
+			case 2:
+				tx.Unlink(n)
+			case 3:
+				tx.Move(n, other, r.Intn(3)-1)
+			case 4:
+				tx.SetValue(n, -i)
+			}
+		}
+
+		if err := tx.Rollback(); err != nil {
+			t.Fatal("Rollback failed:", err)
+		}
+		after := snapshotLinks(nodes)
+		for n, l := range before {
+			if after[n] != l {
+				t.Fatal("Rollback should restore every link and value")
+			}
+		}
+	}
+}
+
+func TestTxCommit(t *testing.T) {
+	root := GenerateStringTree()
+	tx := ntree.Begin(root)
+	tx.Unlink(root.Children)
+	tx.SetValue(root, "x")
+	if err := tx.Commit(); err != nil {
+		t.Fatal("Commit failed:", err)
+	}
+	checkChildren(t, root, "b", "")
+
+	if tx.Rollback() != ntree.ErrTxDone || tx.Commit() != ntree.ErrTxDone || tx.Move(root.Children, root, 0) != ntree.ErrTxDone {
+		t.Error("A finished transaction should refuse further use")
+	}
+	if tx.AppendChild(root, ntree.New("y")) != nil {
+		t.Error("A finished transaction should not change the tree")
+	}
+	checkChildren(t, root, "b", "")
+}
+
+func TestSyncTree(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	nodes := GenerateRandomTree(r, 50)
+	s := ntree.NewSyncTree(nodes[0])
+	failed := errors.New("failed")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				s.View(func(root *ntree.Node) error {
+					if count := ntree.NodeCount(root, ntree.TraverseAll); count != 50 {
+						t.Error("Readers should only see committed states, got", count, "nodes")
+					}
+					return nil
+				})
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		n, other := nodes[1+r.Intn(len(nodes)-1)], nodes[r.Intn(len(nodes))]
+		fail := r.Intn(2) == 0
+		err := s.Update(func(tx *ntree.Tx) error {
+			extra := tx.AppendChild(tx.Root(), ntree.New(-1))
+			tx.Move(n, other, -1)
+			if fail {
+				return failed
+			}
+			tx.Unlink(extra)
+			return nil
+		})
+		if fail != (err == failed) {
+			t.Fatal("Update should return the error of its function")
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,172 @@
+package ntree
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// XMLElement is the Value FromXML gives each element node.  Text holds the element's own character data,
+// concatenated when it is split by child elements.  Names carry namespace URLs rather than prefixes, so Attr holds
+// no xmlns declarations; ToXML declares the namespaces it needs.
+type XMLElement struct {
+	Name xml.Name
+	Attr []xml.Attr
+	Text string
+}
+
+// XMLOptions controls which tokens FromXML keeps.  Whitespace-only character data is dropped unless
+// KeepWhitespace is set.  Kept comments and processing instructions become leaf nodes with xml.Comment and
+// xml.ProcInst values.
+type XMLOptions struct {
+	KeepWhitespace bool
+	KeepComments   bool
+	KeepProcInsts  bool
+}
+
+// XMLNodeMapper returns what ToXML should write for a node: an XMLElement or *XMLElement, or for leaves an
+// xml.Comment, xml.ProcInst, xml.Directive or xml.CharData.
+type XMLNodeMapper func(n *Node) (interface{}, error)
+
+// FromXML reads an XML document from r token by token and returns its document element as a tree.
+// Comments and processing instructions outside the document element are ignored.
+func FromXML(r io.Reader, opts XMLOptions) (*Node, error) {
+	d := xml.NewDecoder(r)
+
+	var root, current *Node
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root != nil && current == nil {
+				return nil, errors.New("ntree: multiple document elements")
+			}
+			t = t.Copy()
+			n := New(&XMLElement{Name: t.Name, Attr: withoutNamespaceDecls(t.Attr)})
+			if current == nil {
+				root = n
+			} else {
+				AppendChild(current, n)
+			}
+			current = n
+		case xml.EndElement:
+			current = current.Parent
+		case xml.CharData:
+			if current == nil {
+				continue
+			}
+			if !opts.KeepWhitespace && len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			current.Value.(*XMLElement).Text += string(t)
+		case xml.Comment:
+			if current != nil && opts.KeepComments {
+				AppendChild(current, New(t.Copy()))
+			}
+		case xml.ProcInst:
+			if current != nil && opts.KeepProcInsts {
+				AppendChild(current, New(t.Copy()))
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("ntree: no document element")
+	}
+	return root, nil
+}
+
+// ToXML writes the tree rooted at root to w as XML, asking mapper what to write for each node.  A nil mapper
+// uses each node's Value, as produced by FromXML.
+func ToXML(w io.Writer, root *Node, mapper XMLNodeMapper) error {
+	if root == nil {
+		return errors.New("ntree: nil root")
+	}
+	if mapper == nil {
+		mapper = func(n *Node) (interface{}, error) {
+			return n.Value, nil
+		}
+	}
+
+	e := xml.NewEncoder(w)
+	var names []xml.Name
+	for n := root; n != nil; {
+		v, err := mapper(n)
+		if err != nil {
+			return err
+		}
+
+		var element *XMLElement
+		switch t := v.(type) {
+		case XMLElement:
+			element = &t
+		case *XMLElement:
+			element = t
+		case xml.Comment, xml.ProcInst, xml.Directive, xml.CharData:
+			if n.Children != nil {
+				return fmt.Errorf("ntree: %T node cannot have children", v)
+			}
+			if err := e.EncodeToken(t); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("ntree: cannot write %T as XML", v)
+		}
+
+		if element != nil {
+			start := xml.StartElement{Name: element.Name, Attr: withoutNamespaceDecls(element.Attr)}
+			if err := e.EncodeToken(start); err != nil {
+				return err
+			}
+			if element.Text != "" {
+				if err := e.EncodeToken(xml.CharData(element.Text)); err != nil {
+					return err
+				}
+			}
+			if n.Children != nil {
+				names = append(names, element.Name)
+				n = n.Children
+				continue
+			}
+			if err := e.EncodeToken(xml.EndElement{Name: element.Name}); err != nil {
+				return err
+			}
+		}
+
+		for n != root && n.Next == nil {
+			n = n.Parent
+			if err := e.EncodeToken(xml.EndElement{Name: names[len(names)-1]}); err != nil {
+				return err
+			}
+			names = names[:len(names)-1]
+		}
+		if n == root {
+			break
+		}
+		n = n.Next
+	}
+
+	return e.Flush()
+}
+
+// withoutNamespaceDecls drops xmlns and xmlns:prefix attributes, which encoding/xml writes itself from the
+// namespaces of the names.
+func withoutNamespaceDecls(attrs []xml.Attr) []xml.Attr {
+	var kept []xml.Attr
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
@@ -0,0 +1,123 @@
+package ntree_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	ntree "github.com/blazingorb/ntreego"
+)
+
+const testXML = `<?xml version="1.0"?>
+<config version="2">
+  <!-- servers -->
+  <server name="a">10.0.0.1</server>
+  <server name="b"><?check fast?>10.0.0.2</server>
+  <empty/>
+</config>`
+
+func TestFromXML(t *testing.T) {
+	root, err := ntree.FromXML(strings.NewReader(testXML), ntree.XMLOptions{})
+	if err != nil {
+		t.Fatal("FromXML failed:", err)
+	}
+
+	config := root.Value.(*ntree.XMLElement)
+	if config.Name.Local != "config" || len(config.Attr) != 1 || config.Attr[0].Value != "2" || config.Text != "" {
+		t.Error("Unexpected document element", config)
+	}
+	if ntree.NodeCount(root, ntree.TraverseAll) != 4 {
+		t.Error("Comments and processing instructions should be dropped by default")
+	}
+
+	server := root.Children.Next.Value.(*ntree.XMLElement)
+	if server.Name.Local != "server" || server.Attr[0].Value != "b" || server.Text != "10.0.0.2" {
+		t.Error("Unexpected server element", server)
+	}
+
+	root, err = ntree.FromXML(strings.NewReader(testXML), ntree.XMLOptions{KeepWhitespace: true, KeepComments: true, KeepProcInsts: true})
+	if err != nil {
+		t.Fatal("FromXML failed:", err)
+	}
+	if comment, ok := root.Children.Value.(xml.Comment); !ok || string(comment) != " servers " {
+		t.Error("Comment should be kept", root.Children.Value)
+	}
+	if pi, ok := root.Children.Next.Next.Children.Value.(xml.ProcInst); !ok || pi.Target != "check" {
+		t.Error("Processing instruction should be kept", root.Children.Next.Next.Children.Value)
+	}
+	if strings.TrimSpace(root.Value.(*ntree.XMLElement).Text) != "" || root.Value.(*ntree.XMLElement).Text == "" {
+		t.Error("Whitespace should be kept")
+	}
+
+	for _, bad := range []string{``, `<a>`, `<a></b>`, `<a/><b/>`} {
+		if _, err := ntree.FromXML(strings.NewReader(bad), ntree.XMLOptions{}); err == nil {
+			t.Errorf("FromXML should fail for %q", bad)
+		}
+	}
+}
+
+func TestToXML(t *testing.T) {
+	root, err := ntree.FromXML(strings.NewReader(testXML), ntree.XMLOptions{KeepComments: true, KeepProcInsts: true})
+	if err != nil {
+		t.Fatal("FromXML failed:", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ntree.ToXML(&buf, root, nil); err != nil {
+		t.Fatal("ToXML failed:", err)
+	}
+	expected := `<config version="2"><!-- servers --><server name="a">10.0.0.1</server><server name="b">10.0.0.2<?check fast?></server><empty></empty></config>`
+	if buf.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, buf.String())
+	}
+
+	again, err := ntree.FromXML(&buf, ntree.XMLOptions{KeepComments: true, KeepProcInsts: true})
+	if err != nil || ntree.NodeCount(again, ntree.TraverseAll) != ntree.NodeCount(root, ntree.TraverseAll) {
+		t.Error("ToXML output should parse back to the same shape", err)
+	}
+
+	buf.Reset()
+	tree := GenerateStringTree()
+	err = ntree.ToXML(&buf, tree, func(n *ntree.Node) (interface{}, error) {
+		return ntree.XMLElement{Name: xml.Name{Local: "node"}, Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: n.Value.(string)}}}, nil
+	})
+	if err != nil || !bytes.HasPrefix(buf.Bytes(), []byte(`<node id="root"><node id="a"><node id="a1"></node>`)) {
+		t.Error("ToXML with a mapper failed", err, buf.String())
+	}
+
+	if ntree.ToXML(&buf, ntree.New(1), nil) == nil {
+		t.Error("ToXML should fail for values it cannot write")
+	}
+	comment := ntree.New(xml.Comment("c"))
+	ntree.AppendChild(comment, ntree.New(xml.Comment("d")))
+	if ntree.ToXML(&buf, comment, nil) == nil {
+		t.Error("ToXML should fail for a comment with children")
+	}
+}
+
+func TestXMLNamespaces(t *testing.T) {
+	const doc = `<a xmlns="urn:x" xmlns:p="urn:p"><p:b p:attr="1"/><c/></a>`
+	root, err := ntree.FromXML(strings.NewReader(doc), ntree.XMLOptions{})
+	if err != nil {
+		t.Fatal("FromXML failed:", err)
+	}
+	if a := root.Value.(*ntree.XMLElement); a.Name.Space != "urn:x" || len(a.Attr) != 0 {
+		t.Error("Namespace declarations should not be kept as attributes", a)
+	}
+
+	var buf bytes.Buffer
+	if err := ntree.ToXML(&buf, root, nil); err != nil {
+		t.Fatal("ToXML failed:", err)
+	}
+	again, err := ntree.FromXML(&buf, ntree.XMLOptions{})
+	if err != nil {
+		t.Fatal("ToXML output should be well-formed:", err, buf.String())
+	}
+	if !ntree.Equal(root, again, func(x, y interface{}) bool {
+		return reflect.DeepEqual(x, y)
+	}) {
+		t.Error("ToXML output should parse back to an equal tree", buf.String())
+	}
+}